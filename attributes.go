@@ -0,0 +1,93 @@
+package ffxivapi
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+// Attributes holds the stats of a character, which depend on the class or job it is currently playing as
+type Attributes struct {
+	// ClassJob is the name of the active class or job these attributes were computed for
+	ClassJob string
+
+	Strength     int
+	Dexterity    int
+	Vitality     int
+	Intelligence int
+	Mind         int
+
+	CriticalHit   int
+	Determination int
+	DirectHit     int
+
+	Defense      int
+	MagicDefense int
+
+	AttackPower         int
+	SkillSpeed          int
+	AttackMagicPotency  int
+	HealingMagicPotency int
+	SpellSpeed          int
+
+	// Tenacity is only reported for tanks, and Piety for healers
+	Tenacity int
+	Piety    int
+
+	// Only one of MP, CP and GP is reported, depending on the discipline of the active class or job
+	HP int
+	MP int
+	CP int
+	GP int
+}
+
+// parseAttributes returns the attributes listed in the profile page of a character, for the given active class or job
+func parseAttributes(doc *goquery.Document, loc *locale, classJob string) Attributes {
+	a := Attributes{ClassJob: classJob}
+
+	// fields maps the english labels used in the lodestone to the attribute they hold
+	fields := map[string]*int{
+		"Strength":              &a.Strength,
+		"Dexterity":             &a.Dexterity,
+		"Vitality":              &a.Vitality,
+		"Intelligence":          &a.Intelligence,
+		"Mind":                  &a.Mind,
+		"Critical Hit Rate":     &a.CriticalHit,
+		"Determination":         &a.Determination,
+		"Direct Hit Rate":       &a.DirectHit,
+		"Defense":               &a.Defense,
+		"Magic Defense":         &a.MagicDefense,
+		"Attack Power":          &a.AttackPower,
+		"Skill Speed":           &a.SkillSpeed,
+		"Attack Magic Potency":  &a.AttackMagicPotency,
+		"Healing Magic Potency": &a.HealingMagicPotency,
+		"Spell Speed":           &a.SpellSpeed,
+		"Tenacity":              &a.Tenacity,
+		"Piety":                 &a.Piety,
+		"HP":                    &a.HP,
+		"MP":                    &a.MP,
+		"CP":                    &a.CP,
+		"GP":                    &a.GP,
+	}
+
+	// Attributes are split in several tables, one per category
+	doc.Find(".character__param__list tr").Each(func(i int, sel *goquery.Selection) {
+		field, found := fields[loc.attribute(strings.TrimSpace(sel.Find("th").First().Text()))]
+		if !found {
+			return
+		}
+
+		*field = silentAtoi(strings.TrimSpace(sel.Find("td").First().Text()))
+	})
+
+	// HP and MP/CP/GP are displayed separately as a label followed by the value
+	doc.Find(".character__param > li").Each(func(i int, sel *goquery.Selection) {
+		field, found := fields[loc.attribute(strings.TrimSpace(sel.Find("p").First().Text()))]
+		if !found {
+			return
+		}
+
+		*field = silentAtoi(strings.TrimSpace(sel.Find("span").First().Text()))
+	})
+
+	return a
+}
@@ -0,0 +1,34 @@
+package ffxivapi
+
+import (
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+)
+
+func TestParseAttributes(t *testing.T) {
+	loc := locales[lodestone.LanguageEnglish]
+
+	for _, tc := range []struct {
+		name     string
+		fixture  string
+		expected Attributes
+	}{
+		{
+			name:    "healer",
+			fixture: "character_attributes.html",
+			expected: Attributes{
+				ClassJob: "White Mage",
+				Strength: 142, Dexterity: 368, Vitality: 3120, Intelligence: 405, Mind: 3280,
+				CriticalHit: 2280, Determination: 1790, DirectHit: 1100,
+				Defense: 2652, MagicDefense: 4630,
+				AttackPower: 142, SkillSpeed: 400, AttackMagicPotency: 2350, HealingMagicPotency: 3280, SpellSpeed: 900,
+				Piety: 1340, HP: 63480, MP: 10000,
+			},
+		},
+		{name: "no attributes", fixture: "empty.html", expected: Attributes{ClassJob: "White Mage"}},
+	} {
+		if a := parseAttributes(fixtureDocument(t, tc.fixture), loc, "White Mage"); a != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, a)
+		}
+	}
+}
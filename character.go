@@ -1,10 +1,9 @@
 package ffxivapi
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,21 +14,38 @@ import (
 const (
 	FeatureClassJob     = 1 << 1
 	FeatureAchievements = 1 << 2
+	FeatureGear         = 1 << 3
+	FeatureMounts       = 1 << 4
+	FeatureMinions      = 1 << 5
 )
 
 // Character models FFXIV character data
 type Character struct {
 	ParsedAt time.Time
 
-	ID    int
-	World string
+	ID         int
+	World      string
+	DataCenter string
+	Region     string
 
 	Avatar   string
 	Portrait string
 
-	Name    string
-	Nameday string
-	City    string
+	Name  string
+	Title struct {
+		Name string
+		// Prefix is true if the title is displayed before the character name, and false if after it
+		Prefix bool
+	}
+
+	Race     Race
+	Clan     Clan
+	Gender   Gender
+	Nameday  Nameday
+	Guardian Guardian
+	City     string
+	// Bio is the self-introduction written by the player, one line per non-empty line of it
+	Bio string
 
 	GC struct {
 		Name string
@@ -41,10 +57,79 @@ type Character struct {
 		Name string
 	}
 
+	PvPTeam struct {
+		ID   string
+		Name string
+	}
+
+	Attributes Attributes
+
 	ClassJobs    []ClassJob
 	Achievements []Achievement
+	Gear         *Gear
+	Mounts       *Collection
+	Minions      *Collection
+
+	// Errors holds the reasons why some of the requested features could not be fully retrieved, if any
+	Errors []*FeatureError `json:",omitempty"`
+}
+
+// Partial returns whether some of the requested features could not be fully retrieved
+func (c *Character) Partial() bool {
+	return len(c.Errors) > 0
 }
 
+// Gender is the gender of a character, as displayed by the lodestone
+type Gender string
+
+const (
+	GenderMale   Gender = "male"
+	GenderFemale Gender = "female"
+)
+
+// genderSymbols maps the symbols used by the lodestone to the gender they represent
+var genderSymbols = map[string]Gender{
+	"♂": GenderMale,
+	"♀": GenderFemale,
+}
+
+// Race is the race of a character. Races are reported in english regardless of the language of the lodestone
+type Race string
+
+const (
+	RaceHyur     Race = "Hyur"
+	RaceElezen   Race = "Elezen"
+	RaceLalafell Race = "Lalafell"
+	RaceMiqote   Race = "Miqo'te"
+	RaceRoegadyn Race = "Roegadyn"
+	RaceAuRa     Race = "Au Ra"
+	RaceHrothgar Race = "Hrothgar"
+	RaceViera    Race = "Viera"
+)
+
+// Clan is the clan of a character within its race. Clans are reported in english regardless of the language of the
+// lodestone
+type Clan string
+
+const (
+	ClanMidlander       Clan = "Midlander"
+	ClanHighlander      Clan = "Highlander"
+	ClanWildwood        Clan = "Wildwood"
+	ClanDuskwight       Clan = "Duskwight"
+	ClanPlainsfolk      Clan = "Plainsfolk"
+	ClanDunesfolk       Clan = "Dunesfolk"
+	ClanSeekerOfTheSun  Clan = "Seeker of the Sun"
+	ClanKeeperOfTheMoon Clan = "Keeper of the Moon"
+	ClanSeaWolf         Clan = "Sea Wolf"
+	ClanHellsguard      Clan = "Hellsguard"
+	ClanRaen            Clan = "Raen"
+	ClanXaela           Clan = "Xaela"
+	ClanHelions         Clan = "Helions"
+	ClanTheLost         Clan = "The Lost"
+	ClanRava            Clan = "Rava"
+	ClanVeena           Clan = "Veena"
+)
+
 // ClassJob stores the progress of a character in a given class or job
 type ClassJob struct {
 	Name  string
@@ -52,6 +137,9 @@ type ClassJob struct {
 
 	Exp     int64
 	ExpNext int64
+
+	Role       string
+	Discipline string
 }
 
 // Achievement contains name, ID and unlocking time for a achievements
@@ -64,48 +152,91 @@ type Achievement struct {
 // urlIdRegex is used to extract IDs from lodestone urls, such as 31688528 in https://eu.finalfantasyxiv.com/lodestone/character/31688528/
 var urlIdRegex = regexp.MustCompile(`/(\d+)/?$`)
 
+// hexIdRegex is the counterpart of urlIdRegex for hex IDs, used for PvP teams and cross-world linkshells
+var hexIdRegex = regexp.MustCompile(`/([0-9a-f]+)/?$`)
+
 // Character returns character data given its ID
-// Achievements and non-active classes and jobs will be returned if features bitmask contains the respective bits
+// Achievements, non-active classes and jobs, equipped gear, mounts and minions will be returned if features bitmask contains the respective bits
 func (api *FFXIVAPI) Character(id int, features uint) (*Character, error) {
-	doc, err := api.lodestone(fmt.Sprintf("/lodestone/character/%d/", id), nil)
+	return api.CharacterContext(context.Background(), id, features)
+}
+
+// CharacterContext behaves like Character, aborting every Lodestone request, including those for features, if ctx is done
+func (api *FFXIVAPI) CharacterContext(ctx context.Context, id int, features uint) (*Character, error) {
+	doc, err := api.lodestone(ctx, fmt.Sprintf("/lodestone/character/%d/", id), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	wg := &sync.WaitGroup{}
+	errs := &featureErrors{}
 
 	character := &Character{ID: id, ParsedAt: time.Now()}
 
-	// Features (achievements and secondary classes and jobs) are queried in parallel
+	// Features living in other pages (achievements, secondary classes and jobs, mounts and minions) are queried in parallel
 	if features&FeatureClassJob != 0 {
 		wg.Add(1)
-		go api.parseClassJob(character, wg)
+		go api.parseClassJob(ctx, character, wg, errs)
 	}
 	if features&FeatureAchievements != 0 {
 		wg.Add(1)
-		go api.parseAchievements(character, wg)
+		go api.parseAchievements(ctx, character, wg, errs)
+	}
+	if features&FeatureMounts != 0 {
+		wg.Add(1)
+		go api.parseMounts(ctx, character, wg, errs)
+	}
+	if features&FeatureMinions != 0 {
+		wg.Add(1)
+		go api.parseMinions(ctx, character, wg, errs)
 	}
 
 	character.Name = doc.Find(".frame__chara__name").First().Text()
-	character.World = doc.Find(".frame__chara__world").First().Text()
+	world := splitWorld(doc.Find(".frame__chara__world").First().Text())
+	character.World = world.Name
+	character.DataCenter = world.DataCenter
+	character.Region = world.Region
+
+	// Title element is placed before or after the name depending on whether it is a prefix or a suffix
+	title := doc.Find(".frame__chara__title").First()
+	character.Title.Name = strings.TrimSpace(title.Text())
+	character.Title.Prefix = title.NextAllFiltered(".frame__chara__name").Length() > 0
+
+	// Active class is identified by its icon, and its level label ("LEVEL 80", "レベル 80"...) depends on the language
+	loc := api.locale(ctx)
+	active := ClassJob{
+		Name:  loc.classJob(classImgMap[doc.Find(".character__class_icon > img").First().AttrOr("src", "")]),
+		Level: firstNumber(doc.Find(".character__class__data > p").Text()),
+	}
 
-	character.ClassJobs = append(character.ClassJobs, ClassJob{
-		Name:  classImgMap[doc.Find(".character__class_icon > img").First().AttrOr("src", "")],
-		Level: silentAtoi(strings.ReplaceAll(strings.TrimSpace(doc.Find(".character__class__data > p").Text()), "LEVEL ", "")),
-	})
+	character.Attributes = parseAttributes(doc, loc, active.Name)
 
 	character.Avatar = doc.Find(".frame__chara__face > img").First().AttrOr("src", "")
 	character.Portrait = doc.Find(".character__detail__image > a > img").First().AttrOr("src", "")
 
-	character.Nameday = doc.Find(".character-block__birth").First().Text()
+	character.Nameday = parseNameday(strings.TrimSpace(doc.Find(".character-block__birth").First().Text()), loc)
 
 	details := doc.Find(".character__profile__data__detail").Children()
+
+	// Race and clan are separated by a line break, and clan and gender by a slash, as in "Hyur<br/>Midlander / ♀"
+	raceClanGender := htmlLines(details.Eq(0).Find(".character-block__name").First())
+	if len(raceClanGender) >= 2 {
+		character.Race = loc.race(raceClanGender[0])
+		clanGender := strings.Split(raceClanGender[1], " / ")
+		character.Clan = loc.clan(clanGender[0])
+		if len(clanGender) >= 2 {
+			character.Gender = genderSymbols[clanGender[1]]
+		}
+	}
+
+	character.Guardian = loc.guardian(strings.TrimSpace(details.Eq(1).Find(".character-block__name").First().Text()))
 	character.City = details.Eq(2).Find(".character-block__name").Text()
 
-	gc := strings.Split(details.Eq(3).Find(".character-block__name").Text(), " / ")
+	// Spacing around the slash separating grand company and rank varies between languages
+	gc := strings.SplitN(details.Eq(3).Find(".character-block__name").Text(), "/", 2)
 	if len(gc) >= 2 {
-		character.GC.Name = gc[0]
-		character.GC.Rank = gc[1]
+		character.GC.Name = strings.TrimSpace(gc[0])
+		character.GC.Rank = strings.TrimSpace(gc[1])
 	}
 
 	fc := doc.Find(".character__freecompany__name").Find("a").First()
@@ -115,82 +246,123 @@ func (api *FFXIVAPI) Character(id int, features uint) (*Character, error) {
 		character.FC.ID = matches[1]
 	}
 
+	character.Bio = strings.Join(htmlLines(doc.Find(".character__selfintroduction").First()), "\n")
+
+	pvpTeam := doc.Find(".character__pvpteam__name").Find("a").First()
+	matches = hexIdRegex.FindStringSubmatch(pvpTeam.AttrOr("href", ""))
+	if len(matches) >= 2 {
+		character.PvPTeam.Name = pvpTeam.Text()
+		character.PvPTeam.ID = matches[1]
+	}
+
+	// Gear is listed in the profile page itself, so it does not need to be queried in parallel
+	if features&FeatureGear != 0 {
+		character.Gear = parseGear(doc)
+	}
+
 	wg.Wait()
-	return character, nil
-}
+	character.Errors = errs.list
 
-func (api *FFXIVAPI) parseClassJob(c *Character, wg *sync.WaitGroup) error {
-	defer wg.Done()
-	return nil
-}
+	// Fall back to the active class if the full list was not requested or could not be retrieved
+	if len(character.ClassJobs) == 0 {
+		character.ClassJobs = append(character.ClassJobs, active)
+	}
 
-var achPageRegex = regexp.MustCompile(`\?page=(\d+)`)
+	return character, nil
+}
 
-func (api *FFXIVAPI) parseAchievements(c *Character, wg *sync.WaitGroup) error {
+func (api *FFXIVAPI) parseClassJob(ctx context.Context, c *Character, wg *sync.WaitGroup, errs *featureErrors) {
 	defer wg.Done()
 
-	// Query first page of achievements
-	doc, err := api.lodestone(fmt.Sprintf("/lodestone/character/%d/achievement/", c.ID), nil)
+	doc, err := api.lodestone(ctx, fmt.Sprintf("/lodestone/character/%d/class_job/", c.ID), nil)
 	if err != nil {
-		return err
+		errs.add(FeatureNameClassJob, 0, err)
+		return
 	}
 
-	// Find index of last page
-	lastPageUrl, public := doc.Find(".btn__pager__next--all").First().Attr("href")
-	if !public {
-		return errors.New(fmt.Sprintf("could not find achievements for %d", c.ID))
-	}
+	classJobs := make([]ClassJob, 0, len(classImgMap))
+	loc := api.locale(ctx)
 
-	lastPage := 0
-	matches := achPageRegex.FindStringSubmatch(lastPageUrl)
-	if len(matches) >= 2 {
-		lastPage = silentAtoi(matches[1])
-	}
+	// Jobs are grouped in blocks by role, each one with a heading followed by the list of jobs
+	doc.Find(".character__job__role").Each(func(i int, role *goquery.Selection) {
+		roleName := strings.TrimSpace(role.Find(".heading--lead").First().Text())
 
-	achvChan := make(chan []Achievement, 8)
-	errChan := make(chan error, 8)
-
-	// Parse first page asynchronously
-	go func() {
-		achvChan <- parseAchievementPage(doc)
-	}()
-
-	// For next pages, if any, parse asyncrhonously as well
-	for p := 2; p <= lastPage; p++ {
-		page := p
-		go func() {
-			doc, err := api.lodestone(fmt.Sprintf("/lodestone/character/%d/achievement", c.ID), map[string]string{
-				"page": fmt.Sprint(page),
-			})
-			if err != nil {
-				errChan <- err
+		role.Find(".character__job > li").Each(func(i int, sel *goquery.Selection) {
+			name := strings.TrimSpace(sel.Find(".character__job__name").First().Text())
+			if name == "" {
 				return
 			}
-			achvChan <- parseAchievementPage(doc)
-		}()
-	}
 
-	// Collect updates from each page and append them to the character's achievement list
-	for p := 1; p <= lastPage; p++ {
-		select {
-		case advs := <-achvChan:
-			c.Achievements = append(c.Achievements, advs...)
-		case err := <-errChan:
-			log.Println(err)
-		}
+			cj := ClassJob{
+				Name:       name,
+				Level:      silentAtoi(strings.TrimSpace(sel.Find(".character__job__level").First().Text())),
+				Role:       roleName,
+				Discipline: loc.disciplines[roleName],
+			}
+
+			// Experience is displayed as "current / next", or "-- / --" if the job is capped or locked
+			exp := strings.Split(sel.Find(".character__job__exp").First().Text(), "/")
+			if len(exp) == 2 {
+				cj.Exp = parseExp(exp[0])
+				cj.ExpNext = parseExp(exp[1])
+			}
+
+			classJobs = append(classJobs, cj)
+		})
+	})
+
+	if len(classJobs) == 0 {
+		errs.add(FeatureNameClassJob, 0, ErrParse)
+		return
 	}
 
-	return nil
+	c.ClassJobs = classJobs
 }
 
-// achNameRegex obtains the achievement name from the flavour text
-var achNameRegex = regexp.MustCompile(`achievement "(.+)" earned`)
+// parseExp parses a comma-separated experience amount, returning 0 for placeholders such as "--"
+func parseExp(s string) int64 {
+	exp, _ := strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 10, 64)
+	return exp
+}
 
-// achDatetimeRegex obtains the unix timestamp from the js code used by the lodestone to display dates
-var achDatetimeRegex = regexp.MustCompile(`ldst_strftime\((\d+), 'YMD'\)`)
+func (api *FFXIVAPI) parseAchievements(ctx context.Context, c *Character, wg *sync.WaitGroup, errs *featureErrors) {
+	defer wg.Done()
+
+	// Query first page of achievements
+	query := fmt.Sprintf("/lodestone/character/%d/achievement/", c.ID)
+	doc, err := api.lodestone(ctx, query, nil)
+	if err != nil {
+		errs.add(FeatureNameAchievements, 1, err)
+		return
+	}
+
+	// Lodestone replaces the list with a notice if achievements are private. Characters which have not earned any
+	// achievement yet get an empty list instead
+	if doc.Find(".entry__achievement").Length() == 0 {
+		if api.locale(ctx).privateRegex.MatchString(doc.Find(".parts__zero").Text()) {
+			errs.add(FeatureNameAchievements, 0, ErrPrivate)
+		}
+		return
+	}
+
+	// Collect achievements from each page and append them to the character's achievement list
+	api.eachPage(ctx, query, nil, doc, func(page int, doc *goquery.Document, err error) {
+		if err != nil {
+			errs.add(FeatureNameAchievements, page, err)
+			return
+		}
+
+		achvs := parseAchievementPage(doc, api.locale(ctx))
+		if len(achvs) == 0 {
+			errs.add(FeatureNameAchievements, page, ErrParse)
+			return
+		}
+		c.Achievements = append(c.Achievements, achvs...)
+	})
+}
 
 // parseAchievementPage pushes to a channel the list of achievements found in a goquery.Document
-func parseAchievementPage(doc *goquery.Document) []Achievement {
+func parseAchievementPage(doc *goquery.Document, loc *locale) []Achievement {
 	// Preallocate list for 50 achievements (50 per page)
 	achievements := make([]Achievement, 0, 50)
 	doc.Find(".entry__achievement").Each(func(i int, sel *goquery.Selection) {
@@ -214,18 +386,13 @@ func parseAchievementPage(doc *goquery.Document) []Achievement {
 
 		// Obtain name from flavour text
 		name := sel.Find(".entry__activity__txt").Text()
-		matches = achNameRegex.FindStringSubmatch(name)
+		matches = loc.achievementRegex.FindStringSubmatch(name)
 		if len(matches) >= 2 {
 			a.Name = matches[1]
 		}
 
 		// Decode unlock time from js snippet
-		datescript := sel.Find("script").First().Text()
-		matches = achDatetimeRegex.FindStringSubmatch(datescript)
-		if len(matches) >= 2 {
-			ts := silentAtoi(matches[1])
-			a.Obtained = time.Unix(int64(ts), 0)
-		}
+		a.Obtained = lodestoneTime(sel)
 
 		achievements = append(achievements, a)
 	})
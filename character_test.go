@@ -0,0 +1,217 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"roob.re/ffxivapi/lodestone"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nameday returns the nameday expected to be parsed from raw
+func nameday(t *testing.T, raw string) Nameday {
+	t.Helper()
+
+	date, err := ParseEorzeanDate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Nameday{Raw: raw, Date: &date}
+}
+
+func TestCharacter(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/character/31688528/": "character.html",
+		"/lodestone/character/2/":        "character_title_suffix.html",
+	}}
+
+	alyx := &Character{
+		ID:         31688528,
+		World:      "Ragnarok",
+		DataCenter: "Chaos",
+		Region:     GameRegionEU,
+		Avatar:     "https://img2.finalfantasyxiv.com/f/alyx_50x50.jpg",
+		Portrait:   "https://img2.finalfantasyxiv.com/f/alyx_640x873.jpg",
+		Name:       "Alyx Bergen",
+		Race:       RaceMiqote,
+		Clan:       ClanSeekerOfTheSun,
+		Gender:     GenderFemale,
+		Nameday:    nameday(t, "24th Sun of the 2nd Astral Moon"),
+		Guardian:   GuardianNymeia,
+		City:       "Limsa Lominsa",
+		Bio:        "Hello!\nI like <maps>.\nSee you in Limsa.",
+		Attributes: Attributes{ClassJob: "White Mage"},
+		ClassJobs:  []ClassJob{{Name: "White Mage", Level: 90}},
+	}
+	alyx.Title.Name = "Warrior of Light"
+	alyx.Title.Prefix = true
+	alyx.GC.Name = "Maelstrom"
+	alyx.GC.Rank = "Second Storm Lieutenant"
+	alyx.FC.ID = "9237023573225362244"
+	alyx.FC.Name = "Fun Company"
+	alyx.PvPTeam.ID = "f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9"
+	alyx.PvPTeam.Name = "Fun Wolves"
+
+	// Characters without a grand company, free company, PvP team or bio, whose title goes after their name
+	lyse := &Character{
+		ID:         2,
+		World:      "Omega",
+		DataCenter: "Chaos",
+		Region:     GameRegionEU,
+		Avatar:     "https://img2.finalfantasyxiv.com/f/lyse_50x50.jpg",
+		Portrait:   "https://img2.finalfantasyxiv.com/f/lyse_640x873.jpg",
+		Name:       "Lyse Hext",
+		Race:       RaceHyur,
+		Clan:       ClanMidlander,
+		Gender:     GenderMale,
+		Nameday:    nameday(t, "1st Sun of the 6th Umbral Moon"),
+		Guardian:   GuardianRhalgr,
+		City:       "Ul'dah",
+		Attributes: Attributes{ClassJob: "Monk"},
+		ClassJobs:  []ClassJob{{Name: "Monk", Level: 70}},
+	}
+	lyse.Title.Name = "of the Crystal"
+
+	for _, tc := range []struct {
+		name     string
+		id       int
+		expected *Character
+		err      error
+	}{
+		{name: "profile", id: 31688528, expected: alyx},
+		{name: "title suffix", id: 2, expected: lyse},
+		{name: "missing", id: 3, err: lodestone.HTTPError(404)},
+	} {
+		c, err := api.CharacterContext(context.Background(), tc.id, 0)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		c.ParsedAt = time.Time{}
+		if !reflect.DeepEqual(c, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, c)
+		}
+	}
+}
+
+func TestParseClassJob(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/character/1/class_job/": "character_class_job.html",
+		"/lodestone/character/2/class_job/": "empty.html",
+	}}
+
+	for _, tc := range []struct {
+		name     string
+		id       int
+		expected []ClassJob
+		errs     []*FeatureError
+	}{
+		{
+			name: "classes and jobs",
+			id:   1,
+			expected: []ClassJob{
+				{Name: "Paladin", Level: 90, Role: "Tank", Discipline: DisciplineWar},
+				{Name: "Warrior", Level: 72, Exp: 1234567, ExpNext: 3609000, Role: "Tank", Discipline: DisciplineWar},
+				{Name: "Conjurer", Level: 15, Exp: 2500, ExpNext: 11000, Role: "Healer", Discipline: DisciplineMagic},
+				{Name: "Carpenter", Level: 1, ExpNext: 300, Role: "Disciples of the Hand", Discipline: DisciplineHand},
+			},
+		},
+		{
+			name: "unparseable page",
+			id:   2,
+			errs: []*FeatureError{{Feature: FeatureNameClassJob, Err: ErrParse}},
+		},
+		{
+			name: "missing page",
+			id:   3,
+			errs: []*FeatureError{{Feature: FeatureNameClassJob, Err: lodestone.HTTPError(404)}},
+		},
+	} {
+		c := &Character{ID: tc.id}
+		wg, errs := &sync.WaitGroup{}, &featureErrors{}
+		wg.Add(1)
+		api.parseClassJob(context.Background(), c, wg, errs)
+
+		if !reflect.DeepEqual(c.ClassJobs, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, c.ClassJobs)
+		}
+		checkFeatureErrors(t, tc.name, errs.list, tc.errs)
+	}
+}
+
+func TestParseAchievements(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/character/1/achievement/":        "character_achievement.html",
+		"/lodestone/character/1/achievement/?page=2": "character_achievement_2.html",
+		"/lodestone/character/2/achievement/":        "character_achievement_private.html",
+		"/lodestone/character/3/achievement/":        "character_achievement_none.html",
+		"/lodestone/character/4/achievement/":        "character_achievement.html",
+		"/lodestone/character/5/achievement/":        "character_achievement.html",
+		"/lodestone/character/5/achievement/?page=2": "empty.html",
+	}}
+
+	firstPage := []Achievement{
+		{ID: 2, Name: "To Crush Your Enemies I", Obtained: time.Unix(1577836800, 0)},
+		{ID: 1256, Name: "Mapping the Realm: Limsa Lominsa", Obtained: time.Unix(1580515200, 0)},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		id       int
+		expected []Achievement
+		errs     []*FeatureError
+	}{
+		{
+			name:     "every page",
+			id:       1,
+			expected: append(firstPage, Achievement{ID: 758, Name: "Let's Get Physical", Obtained: time.Unix(1583020800, 0)}),
+		},
+		{
+			name: "private",
+			id:   2,
+			errs: []*FeatureError{{Feature: FeatureNameAchievements, Err: ErrPrivate}},
+		},
+		{
+			name: "no achievements",
+			id:   3,
+		},
+		{
+			name:     "missing page",
+			id:       4,
+			expected: firstPage,
+			errs:     []*FeatureError{{Feature: FeatureNameAchievements, Page: 2, Err: lodestone.HTTPError(404)}},
+		},
+		{
+			name:     "unparseable page",
+			id:       5,
+			expected: firstPage,
+			errs:     []*FeatureError{{Feature: FeatureNameAchievements, Page: 2, Err: ErrParse}},
+		},
+		{
+			name: "missing first page",
+			id:   6,
+			errs: []*FeatureError{{Feature: FeatureNameAchievements, Page: 1, Err: lodestone.HTTPError(404)}},
+		},
+	} {
+		c := &Character{ID: tc.id}
+		wg, errs := &sync.WaitGroup{}, &featureErrors{}
+		wg.Add(1)
+		api.parseAchievements(context.Background(), c, wg, errs)
+
+		if !reflect.DeepEqual(c.Achievements, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, c.Achievements)
+		}
+		checkFeatureErrors(t, tc.name, errs.list, tc.errs)
+	}
+}
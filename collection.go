@@ -0,0 +1,90 @@
+package ffxivapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"sync"
+)
+
+// Collection is a list of collectibles, such as mounts or minions, obtained by a character
+type Collection struct {
+	Count int
+	Items []Collectible
+}
+
+// Collectible is a single item of a collection
+type Collectible struct {
+	Name string
+	Icon string
+}
+
+// Mounts returns the mounts obtained by a character given its ID
+func (api *FFXIVAPI) Mounts(id int) (*Collection, error) {
+	return api.MountsContext(context.Background(), id)
+}
+
+// MountsContext behaves like Mounts, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) MountsContext(ctx context.Context, id int) (*Collection, error) {
+	return api.collection(ctx, fmt.Sprintf("/lodestone/character/%d/mount/", id), "mount")
+}
+
+// Minions returns the minions obtained by a character given its ID
+func (api *FFXIVAPI) Minions(id int) (*Collection, error) {
+	return api.MinionsContext(context.Background(), id)
+}
+
+// MinionsContext behaves like Minions, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) MinionsContext(ctx context.Context, id int) (*Collection, error) {
+	return api.collection(ctx, fmt.Sprintf("/lodestone/character/%d/minion/", id), "minion")
+}
+
+// collection parses a collection page. Mount and minion pages share the same layout, with class names prefixed by kind
+func (api *FFXIVAPI) collection(ctx context.Context, query string, kind string) (*Collection, error) {
+	doc, err := api.lodestone(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &Collection{}
+
+	doc.Find(fmt.Sprintf(".%s__list > li", kind)).Each(func(i int, sel *goquery.Selection) {
+		collection.Items = append(collection.Items, Collectible{
+			Name: strings.TrimSpace(sel.Find(fmt.Sprintf(".%s__name", kind)).First().Text()),
+			Icon: sel.Find(fmt.Sprintf(".%s__list__icon img", kind)).First().AttrOr("src", ""),
+		})
+	})
+
+	// Lodestone displays the total, but fall back to the number of items if it cannot be found
+	collection.Count = silentAtoi(strings.TrimSpace(doc.Find(fmt.Sprintf(".%s__sort__total > span", kind)).First().Text()))
+	if collection.Count == 0 {
+		collection.Count = len(collection.Items)
+	}
+
+	return collection, nil
+}
+
+func (api *FFXIVAPI) parseMounts(ctx context.Context, c *Character, wg *sync.WaitGroup, errs *featureErrors) {
+	defer wg.Done()
+
+	mounts, err := api.MountsContext(ctx, c.ID)
+	if err != nil {
+		errs.add(FeatureNameMounts, 0, err)
+		return
+	}
+
+	c.Mounts = mounts
+}
+
+func (api *FFXIVAPI) parseMinions(ctx context.Context, c *Character, wg *sync.WaitGroup, errs *featureErrors) {
+	defer wg.Done()
+
+	minions, err := api.MinionsContext(ctx, c.ID)
+	if err != nil {
+		errs.add(FeatureNameMinions, 0, err)
+		return
+	}
+
+	c.Minions = minions
+}
@@ -0,0 +1,63 @@
+package ffxivapi
+
+import (
+	"context"
+	"reflect"
+	"roob.re/ffxivapi/lodestone"
+	"sync"
+	"testing"
+)
+
+func TestParseCollections(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/character/1/mount/":  "character_mount.html",
+		"/lodestone/character/1/minion/": "character_minion.html",
+		"/lodestone/character/2/mount/":  "empty.html",
+	}}
+
+	mounts := &Collection{
+		// The total displayed by the lodestone is trusted over the number of listed items
+		Count: 143,
+		Items: []Collectible{
+			{Name: "Company Chocobo", Icon: "https://img.finalfantasyxiv.com/lds/pc/global/images/itemicon/company_chocobo.png"},
+			{Name: "Magitek Armor", Icon: "https://img.finalfantasyxiv.com/lds/pc/global/images/itemicon/magitek_armor.png"},
+		},
+	}
+	minions := &Collection{
+		Count: 1,
+		Items: []Collectible{
+			{Name: "Wind-up Cursor", Icon: "https://img.finalfantasyxiv.com/lds/pc/global/images/itemicon/wind_up_cursor.png"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		id      int
+		mounts  *Collection
+		minions *Collection
+		errs    []*FeatureError
+	}{
+		{name: "mounts and minions", id: 1, mounts: mounts, minions: minions},
+		{
+			name:   "no mounts and missing minions",
+			id:     2,
+			mounts: &Collection{},
+			errs:   []*FeatureError{{Feature: FeatureNameMinions, Err: lodestone.HTTPError(404)}},
+		},
+	} {
+		c := &Character{ID: tc.id}
+		wg, errs := &sync.WaitGroup{}, &featureErrors{}
+		wg.Add(2)
+		go api.parseMounts(context.Background(), c, wg, errs)
+		go api.parseMinions(context.Background(), c, wg, errs)
+		wg.Wait()
+
+		if !reflect.DeepEqual(c.Mounts, tc.mounts) {
+			t.Errorf("%s: expected mounts %+v, got %+v", tc.name, tc.mounts, c.Mounts)
+		}
+		if !reflect.DeepEqual(c.Minions, tc.minions) {
+			t.Errorf("%s: expected minions %+v, got %+v", tc.name, tc.minions, c.Minions)
+		}
+		checkFeatureErrors(t, tc.name, errs.list, tc.errs)
+	}
+}
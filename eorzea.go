@@ -0,0 +1,274 @@
+package ffxivapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Eorzean calendar has 12 moons of 32 suns each. Moons alternate between astral and umbral phases, so the 5th astral
+// moon is the 9th moon of the year.
+const (
+	SunsPerMoon  = 32
+	MoonsPerYear = 12
+)
+
+// Moon phases
+const (
+	PhaseAstral = "Astral"
+	PhaseUmbral = "Umbral"
+)
+
+// Guardian is one of the twelve deities of Eorzea, each of them the patron of a moon
+type Guardian string
+
+const (
+	GuardianHalone   Guardian = "Halone, the Fury"
+	GuardianMenphina Guardian = "Menphina, the Lover"
+	GuardianThaliak  Guardian = "Thaliak, the Scholar"
+	GuardianNymeia   Guardian = "Nymeia, the Spinner"
+	GuardianLlymlaen Guardian = "Llymlaen, the Navigator"
+	GuardianOschon   Guardian = "Oschon, the Wanderer"
+	GuardianByregot  Guardian = "Byregot, the Builder"
+	GuardianRhalgr   Guardian = "Rhalgr, the Destroyer"
+	GuardianAzeyma   Guardian = "Azeyma, the Warden"
+	GuardianNaldthal Guardian = "Nald'thal, the Traders"
+	GuardianNophica  Guardian = "Nophica, the Matron"
+	GuardianAlthyk   Guardian = "Althyk, the Keeper"
+)
+
+// guardians holds the patron deity of each moon, starting from the 1st astral moon
+var guardians = [MoonsPerYear]Guardian{
+	GuardianHalone,
+	GuardianMenphina,
+	GuardianThaliak,
+	GuardianNymeia,
+	GuardianLlymlaen,
+	GuardianOschon,
+	GuardianByregot,
+	GuardianRhalgr,
+	GuardianAzeyma,
+	GuardianNaldthal,
+	GuardianNophica,
+	GuardianAlthyk,
+}
+
+// Deity returns the name of the guardian without its epithet, such as "Halone"
+func (g Guardian) Deity() string {
+	return strings.SplitN(string(g), ",", 2)[0]
+}
+
+// EorzeanDate is a day of the Eorzean calendar
+type EorzeanDate struct {
+	// Sun is the day of the moon, from 1 to 32
+	Sun int
+	// Moon is the month of the year, from 1 to 12, regardless of its phase
+	Moon int
+}
+
+// eorzeanDateRegex parses dates as displayed by the english lodestone, such as "22nd Sun of the 5th Astral Moon". Other
+// languages display them differently, and their locale holds a regex capturing the same named groups
+var eorzeanDateRegex = regexp.MustCompile(
+	`^(?P<sun>\d+)(?:st|nd|rd|th) Sun of the (?P<moon>\d)(?:st|nd|rd|th) (?P<phase>Astral|Umbral) Moon$`,
+)
+
+// ParseEorzeanDate parses a date in the format used by the lodestone, such as "22nd Sun of the 5th Astral Moon"
+func ParseEorzeanDate(s string) (EorzeanDate, error) {
+	return parseEorzeanDate(s, eorzeanDateRegex, PhaseUmbral)
+}
+
+// parseEorzeanDate parses a date with a regex capturing its sun, the moon within its phase and the phase, which is
+// umbral if it matches umbral regardless of case
+func parseEorzeanDate(s string, regex *regexp.Regexp, umbral string) (EorzeanDate, error) {
+	matches := regex.FindStringSubmatch(s)
+	if matches == nil {
+		return EorzeanDate{}, fmt.Errorf("%q is not a valid eorzean date", s)
+	}
+
+	date := EorzeanDate{
+		Sun:  silentAtoi(matches[regex.SubexpIndex("sun")]),
+		Moon: 2*silentAtoi(matches[regex.SubexpIndex("moon")]) - 1,
+	}
+	if strings.EqualFold(matches[regex.SubexpIndex("phase")], umbral) {
+		date.Moon++
+	}
+
+	if !date.Valid() {
+		return EorzeanDate{}, fmt.Errorf("%q is not a valid eorzean date", s)
+	}
+
+	return date, nil
+}
+
+// Valid returns whether both sun and moon are within range
+func (d EorzeanDate) Valid() bool {
+	return d.Sun >= 1 && d.Sun <= SunsPerMoon && d.Moon >= 1 && d.Moon <= MoonsPerYear
+}
+
+// Phase returns whether the moon is astral or umbral
+func (d EorzeanDate) Phase() string {
+	if d.Moon%2 == 0 {
+		return PhaseUmbral
+	}
+	return PhaseAstral
+}
+
+// PhaseMoon returns the number of the moon within its phase, from 1 to 6
+func (d EorzeanDate) PhaseMoon() int {
+	return (d.Moon + 1) / 2
+}
+
+// Guardian returns the patron deity of the moon, or an empty string if the date is not valid
+func (d EorzeanDate) Guardian() Guardian {
+	if !d.Valid() {
+		return ""
+	}
+	return guardians[d.Moon-1]
+}
+
+// DayOfYear returns the number of the day within the Eorzean year, from 1 to 384
+func (d EorzeanDate) DayOfYear() int {
+	return (d.Moon-1)*SunsPerMoon + d.Sun
+}
+
+// EarthDate returns the Earth date commonly associated to the Eorzean one in the given year, which maps the nth moon
+// to the nth month and the nth sun to the nth day. Suns beyond the end of the month are mapped to its last day.
+func (d EorzeanDate) EarthDate(year int) time.Time {
+	// Day 0 of the next month is the last day of this one
+	lastDay := time.Date(year, time.Month(d.Moon)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	day := d.Sun
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return time.Date(year, time.Month(d.Moon), day, 0, 0, 0, 0, time.UTC)
+}
+
+// EorzeanDateFromEarth is the inverse of EorzeanDate.EarthDate, returning the Eorzean date associated to t
+func EorzeanDateFromEarth(t time.Time) EorzeanDate {
+	return EorzeanDate{Sun: t.Day(), Moon: int(t.Month())}
+}
+
+// String formats the date as the lodestone does
+func (d EorzeanDate) String() string {
+	return fmt.Sprintf("%s Sun of the %s %s Moon", ordinal(d.Sun), ordinal(d.PhaseMoon()), d.Phase())
+}
+
+// eorzeanDateJSON is the JSON representation of EorzeanDate, which includes the derived fields
+type eorzeanDateJSON struct {
+	Sun       int
+	Moon      int
+	Phase     string
+	PhaseMoon int
+	Guardian  Guardian
+}
+
+func (d EorzeanDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eorzeanDateJSON{
+		Sun:       d.Sun,
+		Moon:      d.Moon,
+		Phase:     d.Phase(),
+		PhaseMoon: d.PhaseMoon(),
+		Guardian:  d.Guardian(),
+	})
+}
+
+func (d *EorzeanDate) UnmarshalJSON(data []byte) error {
+	dj := eorzeanDateJSON{}
+	if err := json.Unmarshal(data, &dj); err != nil {
+		return err
+	}
+
+	d.Sun = dj.Sun
+	d.Moon = dj.Moon
+	return nil
+}
+
+// ordinal returns n followed by its english ordinal suffix
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// Nameday is the birth date of a character, both as displayed by the lodestone and decoded into the Eorzean calendar
+type Nameday struct {
+	Raw string
+	// Date is nil if Raw could not be parsed
+	Date *EorzeanDate
+}
+
+// parseNameday decodes the nameday string displayed by the lodestone in the language of loc
+func parseNameday(raw string, loc *locale) Nameday {
+	nameday := Nameday{Raw: raw}
+	if date, err := parseEorzeanDate(raw, loc.namedayRegex, loc.umbral); err == nil {
+		nameday.Date = &date
+	}
+
+	return nameday
+}
+
+// An Eorzean hour lasts 175 Earth seconds, so time passes 3600/175 times faster in Eorzea
+const (
+	eorzeaRatioNum   = 3600
+	eorzeaRatioDenom = 175
+)
+
+// EorzeaTime is a moment in Eorzean time, which shares its epoch with unix time
+type EorzeaTime struct {
+	// Year starts from 1
+	Year int
+	Date EorzeanDate
+
+	Hour   int
+	Minute int
+}
+
+// Eorzean time units, in Eorzean seconds
+const (
+	eorzeanMinute = 60
+	eorzeanHour   = 60 * eorzeanMinute
+	eorzeanSun    = 24 * eorzeanHour
+	eorzeanMoon   = SunsPerMoon * eorzeanSun
+	eorzeanYear   = MoonsPerYear * eorzeanMoon
+)
+
+// EorzeaTimeFromEarth returns the Eorzean time at the given Earth time
+func EorzeaTimeFromEarth(t time.Time) EorzeaTime {
+	// Milliseconds are used to keep precision, as each Earth second is worth more than 20 Eorzean ones
+	seconds := t.UnixMilli() * eorzeaRatioNum / eorzeaRatioDenom / 1000
+
+	return EorzeaTime{
+		Year: int(seconds/eorzeanYear) + 1,
+		Date: EorzeanDate{
+			Sun:  int(seconds/eorzeanSun%SunsPerMoon) + 1,
+			Moon: int(seconds/eorzeanMoon%MoonsPerYear) + 1,
+		},
+		Hour:   int(seconds / eorzeanHour % 24),
+		Minute: int(seconds / eorzeanMinute % 60),
+	}
+}
+
+// Earth returns the Earth time at which the Eorzean minute et represents starts
+func (et EorzeaTime) Earth() time.Time {
+	seconds := int64(et.Year-1)*eorzeanYear +
+		int64(et.Date.Moon-1)*eorzeanMoon +
+		int64(et.Date.Sun-1)*eorzeanSun +
+		int64(et.Hour)*eorzeanHour +
+		int64(et.Minute)*eorzeanMinute
+
+	// Round up so converting the result back yields the same Eorzean minute
+	return time.UnixMilli((seconds*1000*eorzeaRatioDenom + eorzeaRatioNum - 1) / eorzeaRatioNum)
+}
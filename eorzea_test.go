@@ -0,0 +1,184 @@
+package ffxivapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseEorzeanDate(t *testing.T) {
+	for _, tc := range []struct {
+		raw      string
+		expected EorzeanDate
+		err      bool
+	}{
+		{raw: "1st Sun of the 1st Astral Moon", expected: EorzeanDate{Sun: 1, Moon: 1}},
+		{raw: "2nd Sun of the 1st Umbral Moon", expected: EorzeanDate{Sun: 2, Moon: 2}},
+		{raw: "22nd Sun of the 5th Astral Moon", expected: EorzeanDate{Sun: 22, Moon: 9}},
+		{raw: "13th Sun of the 3rd Umbral Moon", expected: EorzeanDate{Sun: 13, Moon: 6}},
+		{raw: "32nd Sun of the 6th Umbral Moon", expected: EorzeanDate{Sun: 32, Moon: 12}},
+		{raw: "33rd Sun of the 6th Umbral Moon", err: true},
+		{raw: "0th Sun of the 1st Astral Moon", err: true},
+		{raw: "1st Sun of the 7th Astral Moon", err: true},
+		{raw: "1st Sun of the 1st Lunar Moon", err: true},
+		{raw: "", err: true},
+	} {
+		date, err := ParseEorzeanDate(tc.raw)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %+v", tc.raw, date)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.raw, err)
+			continue
+		}
+		if date != tc.expected {
+			t.Errorf("%q: expected %+v, got %+v", tc.raw, tc.expected, date)
+		}
+		if date.String() != tc.raw {
+			t.Errorf("%q: expected to format back to the same string, got %q", tc.raw, date.String())
+		}
+	}
+}
+
+func TestEorzeanDateMoons(t *testing.T) {
+	for _, tc := range []struct {
+		moon      int
+		phase     string
+		phaseMoon int
+		guardian  Guardian
+	}{
+		{moon: 1, phase: PhaseAstral, phaseMoon: 1, guardian: GuardianHalone},
+		{moon: 2, phase: PhaseUmbral, phaseMoon: 1, guardian: GuardianMenphina},
+		{moon: 3, phase: PhaseAstral, phaseMoon: 2, guardian: GuardianThaliak},
+		{moon: 4, phase: PhaseUmbral, phaseMoon: 2, guardian: GuardianNymeia},
+		{moon: 5, phase: PhaseAstral, phaseMoon: 3, guardian: GuardianLlymlaen},
+		{moon: 6, phase: PhaseUmbral, phaseMoon: 3, guardian: GuardianOschon},
+		{moon: 7, phase: PhaseAstral, phaseMoon: 4, guardian: GuardianByregot},
+		{moon: 8, phase: PhaseUmbral, phaseMoon: 4, guardian: GuardianRhalgr},
+		{moon: 9, phase: PhaseAstral, phaseMoon: 5, guardian: GuardianAzeyma},
+		{moon: 10, phase: PhaseUmbral, phaseMoon: 5, guardian: GuardianNaldthal},
+		{moon: 11, phase: PhaseAstral, phaseMoon: 6, guardian: GuardianNophica},
+		{moon: 12, phase: PhaseUmbral, phaseMoon: 6, guardian: GuardianAlthyk},
+		{moon: 13},
+	} {
+		date := EorzeanDate{Sun: 1, Moon: tc.moon}
+		if tc.phase != "" && date.Phase() != tc.phase {
+			t.Errorf("moon %d: expected phase %s, got %s", tc.moon, tc.phase, date.Phase())
+		}
+		if tc.phaseMoon != 0 && date.PhaseMoon() != tc.phaseMoon {
+			t.Errorf("moon %d: expected phase moon %d, got %d", tc.moon, tc.phaseMoon, date.PhaseMoon())
+		}
+		if date.Guardian() != tc.guardian {
+			t.Errorf("moon %d: expected guardian %q, got %q", tc.moon, tc.guardian, date.Guardian())
+		}
+	}
+}
+
+func TestEorzeanDateJSON(t *testing.T) {
+	date := EorzeanDate{Sun: 22, Moon: 9}
+
+	data, err := json.Marshal(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"Sun":22,"Moon":9,"Phase":"Astral","PhaseMoon":5,"Guardian":"Azeyma, the Warden"}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	decoded := EorzeanDate{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != date {
+		t.Errorf("expected %+v, got %+v", date, decoded)
+	}
+}
+
+func TestEorzeanDateEarthDate(t *testing.T) {
+	for _, tc := range []struct {
+		date     EorzeanDate
+		year     int
+		expected string
+	}{
+		{date: EorzeanDate{Sun: 22, Moon: 9}, year: 2020, expected: "2020-09-22"},
+		{date: EorzeanDate{Sun: 31, Moon: 1}, year: 2021, expected: "2021-01-31"},
+		{date: EorzeanDate{Sun: 32, Moon: 1}, year: 2021, expected: "2021-01-31"},
+		{date: EorzeanDate{Sun: 30, Moon: 2}, year: 2021, expected: "2021-02-28"},
+		{date: EorzeanDate{Sun: 30, Moon: 2}, year: 2020, expected: "2020-02-29"},
+	} {
+		earth := tc.date.EarthDate(tc.year)
+		if earth.Format("2006-01-02") != tc.expected {
+			t.Errorf("%v in %d: expected %s, got %s", tc.date, tc.year, tc.expected, earth.Format("2006-01-02"))
+		}
+
+		// Suns beyond the end of the month cannot be recovered
+		if tc.date.Sun <= earth.Day() && EorzeanDateFromEarth(earth) != tc.date {
+			t.Errorf("%v in %d: expected round trip, got %+v", tc.date, tc.year, EorzeanDateFromEarth(earth))
+		}
+	}
+}
+
+func TestEorzeaTimeFromEarth(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		earth    time.Time
+		expected EorzeaTime
+	}{
+		{
+			name:     "epoch",
+			earth:    time.Unix(0, 0),
+			expected: EorzeaTime{Year: 1, Date: EorzeanDate{Sun: 1, Moon: 1}},
+		},
+		{
+			// 175 Earth seconds make an Eorzean hour
+			name:     "one hour",
+			earth:    time.Unix(175, 0),
+			expected: EorzeaTime{Year: 1, Date: EorzeanDate{Sun: 1, Moon: 1}, Hour: 1},
+		},
+		{
+			// 70 Earth minutes make an Eorzean sun
+			name:     "one sun",
+			earth:    time.Unix(70*60, 0),
+			expected: EorzeaTime{Year: 1, Date: EorzeanDate{Sun: 2, Moon: 1}},
+		},
+		{
+			name:     "one year",
+			earth:    time.Unix(70*60*SunsPerMoon*MoonsPerYear, 0),
+			expected: EorzeaTime{Year: 2, Date: EorzeanDate{Sun: 1, Moon: 1}},
+		},
+	} {
+		if et := EorzeaTimeFromEarth(tc.earth); et != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, et)
+		}
+	}
+}
+
+func TestEorzeaTimeRoundTrip(t *testing.T) {
+	for _, earth := range []time.Time{
+		time.Unix(0, 0),
+		time.Unix(1, 0),
+		time.Date(2020, 9, 28, 14, 21, 56, 403712609, time.UTC),
+		time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC),
+	} {
+		et := EorzeaTimeFromEarth(earth)
+
+		start := et.Earth()
+		if start.After(earth) {
+			t.Errorf("%v: Eorzean minute %+v starts after it, at %v", earth, et, start)
+		}
+		if back := EorzeaTimeFromEarth(start); back != et {
+			t.Errorf("%v: expected %+v to convert back to itself, got %+v", earth, et, back)
+		}
+
+		// An Eorzean minute lasts less than three Earth seconds
+		if earth.Sub(start) >= 3*time.Second {
+			t.Errorf("%v: Eorzean minute %+v starts too early, at %v", earth, et, start)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ffxivapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPrivate is returned when the Lodestone does not disclose the requested data, e.g. private achievements
+var ErrPrivate = errors.New("data is private")
+
+// ErrParse is returned when a Lodestone page could be retrieved but its contents could not be understood
+var ErrParse = errors.New("could not parse lodestone page")
+
+// Feature names, as reported in FeatureError
+const (
+	FeatureNameClassJob     = "classjob"
+	FeatureNameAchievements = "achievements"
+	FeatureNameMembers      = "members"
+	FeatureNameMounts       = "mounts"
+	FeatureNameMinions      = "minions"
+	FeatureNameSearch       = "search"
+)
+
+// Feature error reasons, as reported in the JSON representation of FeatureError
+const (
+	ReasonPrivate = "private"
+	ReasonParse   = "parse"
+	// ReasonPage is reported when a single page of a paginated feature could not be retrieved
+	ReasonPage = "page"
+	// ReasonRequest is reported when a feature could not be retrieved, or the request was aborted, and the error is
+	// not tied to a single page
+	ReasonRequest = "request"
+)
+
+// FeatureError is a non-fatal error which prevented a feature, like achievements, from being fully retrieved
+type FeatureError struct {
+	Feature string
+	// Page is the number of the Lodestone page which failed, or 0 if the error is not tied to a single page
+	Page int
+	Err  error
+}
+
+func (fe *FeatureError) Error() string {
+	if fe.Page > 0 {
+		return fmt.Sprintf("%s: page %d: %v", fe.Feature, fe.Page, fe.Err)
+	}
+	return fmt.Sprintf("%s: %v", fe.Feature, fe.Err)
+}
+
+func (fe *FeatureError) Unwrap() error {
+	return fe.Err
+}
+
+// Reason returns a short string classifying the error as one of the Reason* constants
+func (fe *FeatureError) Reason() string {
+	switch {
+	case errors.Is(fe.Err, ErrPrivate):
+		return ReasonPrivate
+	case errors.Is(fe.Err, ErrParse):
+		return ReasonParse
+	case fe.Page > 0:
+		return ReasonPage
+	default:
+		return ReasonRequest
+	}
+}
+
+func (fe *FeatureError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Feature string
+		Page    int `json:",omitempty"`
+		Reason  string
+		Error   string
+	}{
+		Feature: fe.Feature,
+		Page:    fe.Page,
+		Reason:  fe.Reason(),
+		Error:   fe.Err.Error(),
+	})
+}
+
+// featureErrors is a list of FeatureError safe to be appended to from several goroutines
+type featureErrors struct {
+	mtx  sync.Mutex
+	list []*FeatureError
+}
+
+func (fe *featureErrors) add(feature string, page int, err error) {
+	fe.mtx.Lock()
+	defer fe.mtx.Unlock()
+
+	fe.list = append(fe.list, &FeatureError{Feature: feature, Page: page, Err: err})
+}
@@ -0,0 +1,40 @@
+package ffxivapi
+
+import (
+	"context"
+	"fmt"
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+)
+
+func TestFeatureErrorReason(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      *FeatureError
+		expected string
+	}{
+		{name: "private", err: &FeatureError{Feature: FeatureNameAchievements, Err: ErrPrivate}, expected: ReasonPrivate},
+		{name: "parse", err: &FeatureError{Feature: FeatureNameClassJob, Err: ErrParse}, expected: ReasonParse},
+		{name: "parse page", err: &FeatureError{Feature: FeatureNameAchievements, Page: 2, Err: ErrParse}, expected: ReasonParse},
+		{
+			name:     "page",
+			err:      &FeatureError{Feature: FeatureNameAchievements, Page: 2, Err: lodestone.HTTPError(503)},
+			expected: ReasonPage,
+		},
+		{
+			name:     "request",
+			err:      &FeatureError{Feature: FeatureNameMounts, Err: lodestone.HTTPError(503)},
+			expected: ReasonRequest,
+		},
+		{name: "cancelled", err: &FeatureError{Feature: FeatureNameMinions, Err: context.Canceled}, expected: ReasonRequest},
+		{
+			name:     "wrapped private",
+			err:      &FeatureError{Feature: FeatureNameAchievements, Err: fmt.Errorf("page 1: %w", ErrPrivate)},
+			expected: ReasonPrivate,
+		},
+	} {
+		if reason := tc.err.Reason(); reason != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, reason)
+		}
+	}
+}
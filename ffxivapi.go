@@ -1,31 +1,52 @@
 package ffxivapi // import "roob.re/ffxivapi"
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	log "github.com/sirupsen/logrus"
+	"html"
 	"net/http"
 	"net/url"
+	"regexp"
 	"roob.re/ffxivapi/lodestone"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // FFXIVAPI is the main object, containing the region to be targeted and the HTTP client to use
 type FFXIVAPI struct {
+	// Lodestone retrieves pages for the API. Requests made with a lodestone.ContextClient are aborted once the context
+	// of the call which made them is done
 	Lodestone lodestone.Client
+	// Language is used for requests whose context specifies neither a language with lodestone.WithLanguage nor a region
+	// with lodestone.WithRegion, in which case the language of the region is used. Defaults to English
+	Language string
+
+	// requests coalesces identical lodestone queries made concurrently, sharing their parsed document
+	requests lodestone.Group
 }
 
-// New returns a new FFXIVAPI object with http.DefaultClient and the region set to Europe ("eu")
+// New returns a new FFXIVAPI object querying every lodestone region through http.DefaultTransport, throttled by a
+// lodestone.RateLimiter with the default limits shared by all of its requests. Requests are sent to Europe ("eu")
+// unless their context specifies another region with lodestone.WithRegion
 func New() *FFXIVAPI {
-	return &FFXIVAPI{
-		Lodestone: &lodestone.HTTPClient{
-			Server:     lodestone.CanonServerFromRegion("eu"),
-			HTTPClient: http.DefaultClient,
+	client := &http.Client{
+		Transport: &lodestone.RateLimitedRoundTripper{
+			RoundTripper: http.DefaultTransport,
+			Limiter:      lodestone.NewDefaultRateLimiter(),
 		},
 	}
+
+	return &FFXIVAPI{
+		Lodestone: lodestone.NewMultiRegionClient(client, lodestone.RegionEU, nil),
+	}
 }
 
 // lodestone queries the given lodestone URL and params (url-encoding them) and returns a goquery document
-func (api *FFXIVAPI) lodestone(query string, params map[string]string) (*goquery.Document, error) {
+func (api *FFXIVAPI) lodestone(ctx context.Context, query string, params map[string]string) (*goquery.Document, error) {
 	if len(params) > 0 {
 		query += "?"
 		urlValues := url.Values{}
@@ -35,13 +56,122 @@ func (api *FFXIVAPI) lodestone(query string, params map[string]string) (*goquery
 		query += urlValues.Encode()
 	}
 
-	log.Debugf("lodestone: requesting %s", query)
-	response, err := api.Lodestone.Request(query)
+	// Language is always set explicitly so the client requests the same one the document will be parsed with
+	lang := api.language(ctx)
+	ctx = lodestone.WithLanguage(ctx, lang)
+
+	// Documents are only read once parsed, so concurrent identical queries can safely share the same one
+	key := lang + " " + lodestone.RegionFromContext(ctx) + " " + query
+	for {
+		doc, err, shared := api.requests.Do(ctx, key, func() (interface{}, error) {
+			log.Debugf("lodestone: requesting %s (%s)", query, lang)
+			response, err := lodestone.RequestContext(ctx, api.Lodestone, query)
+			if err != nil {
+				return nil, err
+			}
+			defer response.Close()
+
+			return goquery.NewDocumentFromReader(response)
+		})
+
+		// If the caller which made the request gave up, the query is made again on behalf of the rest
+		if shared && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+
+		if shared {
+			log.Debugf("lodestone: coalesced %s (%s)", query, lang)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return doc.(*goquery.Document), nil
+	}
+}
+
+// CoalescingStats returns how many lodestone queries were made, and how many callers shared the result of an
+// identical query which was already in flight instead of making their own
+func (api *FFXIVAPI) CoalescingStats() lodestone.GroupStats {
+	return api.requests.Stats()
+}
+
+// pageRegex obtains the page number from the links of the lodestone pager
+var pageRegex = regexp.MustCompile(`\?page=(\d+)`)
+
+// lastPage returns the number of the last page of a paginated lodestone document, or 1 if it has no pager
+func lastPage(doc *goquery.Document) int {
+	matches := pageRegex.FindStringSubmatch(doc.Find(".btn__pager__next--all").First().AttrOr("href", ""))
+	if len(matches) < 2 {
+		return 1
+	}
+
+	return silentAtoi(matches[1])
+}
+
+// pageResult is a page of a paginated lodestone query, as retrieved by eachPage workers
+type pageResult struct {
+	page int
+	doc  *goquery.Document
+	err  error
+}
+
+// eachPage calls fn with first, the already retrieved first page of a paginated lodestone query, and then with every
+// other page of it, which are retrieved in parallel. fn is always called from the calling goroutine, in no particular
+// page order, and receives a nil doc if the page could not be retrieved
+func (api *FFXIVAPI) eachPage(ctx context.Context, query string, params map[string]string, first *goquery.Document, fn func(page int, doc *goquery.Document, err error)) {
+	last := lastPage(first)
+	results := make(chan pageResult, 8)
+
+	for p := 2; p <= last; p++ {
+		page := p
+		go func() {
+			pageParams := map[string]string{"page": fmt.Sprint(page)}
+			for k, v := range params {
+				pageParams[k] = v
+			}
+
+			doc, err := api.lodestone(ctx, query, pageParams)
+			results <- pageResult{page: page, doc: doc, err: err}
+		}()
+	}
+
+	fn(1, first, nil)
+	for p := 2; p <= last; p++ {
+		result := <-results
+		fn(result.page, result.doc, result.err)
+	}
+}
+
+// brRegex matches html line breaks
+var brRegex = regexp.MustCompile(`<br\s*/?>`)
+
+// tagRegex matches any other html tag
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// htmlLines returns the trimmed, non-empty text lines of sel, as separated by <br> tags
+func htmlLines(sel *goquery.Selection) []string {
+	inner, err := sel.Html()
 	if err != nil {
-		return nil, err
+		return nil
 	}
 
-	return goquery.NewDocumentFromReader(response)
+	var lines []string
+	for _, line := range brRegex.Split(inner, -1) {
+		line = strings.TrimSpace(html.UnescapeString(tagRegex.ReplaceAllString(line, "")))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// numberRegex matches numbers which may contain thousands separators, such as 1,234
+var numberRegex = regexp.MustCompile(`\d[\d,]*`)
+
+// firstNumber returns the first number found in s, or 0 if there is none
+func firstNumber(s string) int {
+	return silentAtoi(strings.ReplaceAll(numberRegex.FindString(s), ",", ""))
 }
 
 // silentAtoi discards error from atoi, used to assign numbers assumed to be correctly-formatted into inline initializers
@@ -49,3 +179,16 @@ func silentAtoi(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
 }
+
+// ldstDatetimeRegex obtains the unix timestamp from the js code used by the lodestone to display dates
+var ldstDatetimeRegex = regexp.MustCompile(`ldst_strftime\((\d+), 'YMD'\)`)
+
+// lodestoneTime decodes the date displayed by the first js snippet found inside sel, returning the zero time if none is found
+func lodestoneTime(sel *goquery.Selection) time.Time {
+	matches := ldstDatetimeRegex.FindStringSubmatch(sel.Find("script").First().Text())
+	if len(matches) < 2 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(silentAtoi(matches[1])), 0)
+}
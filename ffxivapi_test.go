@@ -0,0 +1,195 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"roob.re/ffxivapi/lodestone"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixtureLodestone is a plain lodestone.Client answering each query with the testdata file it is mapped to, and any
+// other query with a 404 error
+type fixtureLodestone map[string]string
+
+func (fl fixtureLodestone) Request(query string) (io.ReadCloser, error) {
+	name, found := fl[query]
+	if !found {
+		return nil, lodestone.HTTPError(http.StatusNotFound)
+	}
+
+	return os.Open(filepath.Join("testdata", name))
+}
+
+// hangingLodestone is a lodestone.ContextClient whose requests only end once their context is done
+type hangingLodestone struct{}
+
+func (hangingLodestone) Request(query string) (io.ReadCloser, error) {
+	return nil, errors.New("requests cannot be made without a context")
+}
+
+func (hangingLodestone) RequestContext(ctx context.Context, query string) (io.ReadCloser, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// fixtureDocument parses the given testdata file
+func fixtureDocument(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// checkFeatureErrors reports an error unless errs holds exactly the expected feature errors, in any order. Errors are
+// compared with errors.Is, so expected ones can be sentinels such as ErrPrivate
+func checkFeatureErrors(t *testing.T, name string, errs []*FeatureError, expected []*FeatureError) {
+	t.Helper()
+
+	if len(errs) != len(expected) {
+		t.Errorf("%s: expected errors %v, got %v", name, expected, errs)
+		return
+	}
+
+	matched := make([]bool, len(errs))
+	for _, e := range expected {
+		found := false
+		for i, err := range errs {
+			if !matched[i] && err.Feature == e.Feature && err.Page == e.Page && errors.Is(err.Err, e.Err) {
+				matched[i], found = true, true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("%s: expected errors %v, got %v", name, expected, errs)
+			return
+		}
+	}
+}
+
+func TestEachPageCancelled(t *testing.T) {
+	first, err := goquery.NewDocumentFromReader(strings.NewReader(
+		`<a href="/lodestone/freecompany/1/member/?page=5" class="btn__pager__next--all"></a>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &FFXIVAPI{Lodestone: hangingLodestone{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	pages := 0
+	api.eachPage(ctx, "/lodestone/freecompany/1/member/", nil, first, func(page int, doc *goquery.Document, err error) {
+		pages++
+		if page > 1 && (doc != nil || !errors.Is(err, context.Canceled)) {
+			t.Errorf("page %d: expected %v, got %v", page, context.Canceled, err)
+		}
+	})
+
+	if pages != 5 {
+		t.Errorf("expected every page to be reported, got %d", pages)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected pages to stop once cancelled, took %v", elapsed)
+	}
+}
+
+// joinedContext is a context reporting, by closing joined, the first time Done is called on it, which lodestone does
+// only once the caller waits for a query made by another one
+type joinedContext struct {
+	context.Context
+	once   sync.Once
+	joined chan struct{}
+}
+
+func (jc *joinedContext) Done() <-chan struct{} {
+	jc.once.Do(func() { close(jc.joined) })
+	return jc.Context.Done()
+}
+
+// firstHangingLodestone is a lodestone.ContextClient whose first request only ends once its context is done, after
+// closing started, while the rest are answered with an empty document
+type firstHangingLodestone struct {
+	mtx      sync.Mutex
+	requests int
+	started  chan struct{}
+}
+
+func (fl *firstHangingLodestone) Request(query string) (io.ReadCloser, error) {
+	return fl.RequestContext(context.Background(), query)
+}
+
+func (fl *firstHangingLodestone) RequestContext(ctx context.Context, query string) (io.ReadCloser, error) {
+	fl.mtx.Lock()
+	fl.requests++
+	first := fl.requests == 1
+	fl.mtx.Unlock()
+
+	if !first {
+		return os.Open(filepath.Join("testdata", "empty.html"))
+	}
+
+	close(fl.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestLodestoneFirstCallerCancelled(t *testing.T) {
+	client := &firstHangingLodestone{started: make(chan struct{})}
+	api := &FFXIVAPI{Lodestone: client}
+
+	firstCtx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := api.lodestone(firstCtx, "/lodestone/character/1/", nil)
+		first <- err
+	}()
+	<-client.started
+
+	waiterCtx := &joinedContext{Context: context.Background(), joined: make(chan struct{})}
+	waiter := make(chan error)
+	go func() {
+		doc, err := api.lodestone(waiterCtx, "/lodestone/character/1/", nil)
+		if err == nil && doc == nil {
+			t.Error("expected waiter to get a document")
+		}
+		waiter <- err
+	}()
+	<-waiterCtx.joined
+
+	// The waiter makes the query again on its own once the first caller gives up
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected first caller to get %v, got %v", context.Canceled, err)
+	}
+	if err := <-waiter; err != nil {
+		t.Errorf("expected waiter to get its result, got %v", err)
+	}
+
+	if client.requests != 2 {
+		t.Errorf("expected 2 lodestone requests, got %d", client.requests)
+	}
+	if stats := api.CoalescingStats(); stats.Calls != 2 || stats.Coalesced != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,228 @@
+package ffxivapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FreeCompany models FFXIV free company data
+type FreeCompany struct {
+	ParsedAt time.Time
+
+	ID         string
+	World      string
+	DataCenter string
+	Region     string
+
+	Name   string
+	Tag    string
+	Slogan string
+	// Crest holds the URLs of the images which, stacked in order, compose the company crest
+	Crest []string
+
+	Formed        time.Time
+	Rank          int
+	ActiveMembers int
+	// Active is the time of the day the company is active, e.g. "Always"
+	Active     string
+	Recruiting bool
+
+	GC struct {
+		Name     string
+		Standing string
+	}
+
+	Estate struct {
+		Name     string
+		Address  string
+		Greeting string
+	}
+
+	Focus      []FreeCompanyFocus
+	Reputation []FreeCompanyReputation
+}
+
+// FreeCompanyFocus is one of the activities a free company can declare to be focused on
+type FreeCompanyFocus struct {
+	Name   string
+	Icon   string
+	Active bool
+}
+
+// FreeCompanyReputation holds the standing of a free company with one of the grand companies
+type FreeCompanyReputation struct {
+	GC       string
+	Standing string
+	// Progress is the percentage of progress towards the next standing
+	Progress int
+}
+
+// fcGCRegex splits the grand company name from the standing, as in "Maelstrom <Allied>"
+var fcGCRegex = regexp.MustCompile(`^(.+?)\s*<(.+)>$`)
+
+// progressRegex obtains the percentage from the inline style of lodestone progress bars
+var progressRegex = regexp.MustCompile(`width:\s*(\d+)%`)
+
+// FreeCompany returns free company data given its ID
+func (api *FFXIVAPI) FreeCompany(id string) (*FreeCompany, error) {
+	return api.FreeCompanyContext(context.Background(), id)
+}
+
+// FreeCompanyContext behaves like FreeCompany, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) FreeCompanyContext(ctx context.Context, id string) (*FreeCompany, error) {
+	doc, err := api.lodestone(ctx, fmt.Sprintf("/lodestone/freecompany/%s/", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &FreeCompany{ID: id, ParsedAt: time.Now()}
+
+	fc.Crest = crestLayers(doc.Selection, ".entry__freecompany__crest__image")
+	fc.Name = strings.TrimSpace(doc.Find(".entry__freecompany__name").First().Text())
+
+	// Header contains two paragraphs with the same class: grand company and world
+	header := doc.Find(".entry__freecompany__box > .entry__freecompany__gc")
+	world := splitWorld(header.Last().Text())
+	fc.World = world.Name
+	fc.DataCenter = world.DataCenter
+	fc.Region = world.Region
+	matches := fcGCRegex.FindStringSubmatch(strings.TrimSpace(header.First().Text()))
+	if len(matches) >= 3 {
+		fc.GC.Name = matches[1]
+		fc.GC.Standing = matches[2]
+	}
+
+	fc.Slogan = strings.TrimSpace(doc.Find(".freecompany__text__message").First().Text())
+	fc.Tag = strings.Trim(strings.TrimSpace(doc.Find(".freecompany__text__tag").First().Text()), "«»")
+
+	// Most fields are plain paragraphs only distinguishable by the heading preceding them
+	loc := api.locale(ctx)
+	fc.Formed = lodestoneTime(fcSection(doc, loc.fcHeadings.formed))
+	fc.ActiveMembers = silentAtoi(strings.TrimSpace(fcSection(doc, loc.fcHeadings.activeMembers).Text()))
+	fc.Rank = silentAtoi(strings.TrimSpace(fcSection(doc, loc.fcHeadings.rank).Text()))
+	fc.Active = strings.TrimSpace(fcSection(doc, loc.fcHeadings.active).Text())
+	fc.Recruiting = strings.TrimSpace(doc.Find(".freecompany__recruitment").First().Text()) == loc.recruitmentOpen
+
+	fc.Estate.Name = strings.TrimSpace(doc.Find(".freecompany__estate__name").First().Text())
+	fc.Estate.Address = strings.TrimSpace(doc.Find(".freecompany__estate__text").First().Text())
+	fc.Estate.Greeting = strings.TrimSpace(doc.Find(".freecompany__estate__greeting").First().Text())
+
+	doc.Find(".freecompany__focus_icon").First().Find("li").Each(func(i int, sel *goquery.Selection) {
+		fc.Focus = append(fc.Focus, FreeCompanyFocus{
+			Name:   strings.TrimSpace(sel.Find("p").First().Text()),
+			Icon:   sel.Find("img").First().AttrOr("src", ""),
+			Active: !sel.HasClass("freecompany__focus_icon--off"),
+		})
+	})
+
+	doc.Find(".freecompany__reputation").Each(func(i int, sel *goquery.Selection) {
+		rep := FreeCompanyReputation{
+			GC:       strings.TrimSpace(sel.Find(".freecompany__reputation__gcname").First().Text()),
+			Standing: strings.TrimSpace(sel.Find(".freecompany__reputation__rank").First().Text()),
+		}
+
+		matches := progressRegex.FindStringSubmatch(sel.Find(".character__bar > div").First().AttrOr("style", ""))
+		if len(matches) >= 2 {
+			rep.Progress = silentAtoi(matches[1])
+		}
+
+		fc.Reputation = append(fc.Reputation, rep)
+	})
+
+	return fc, nil
+}
+
+// crestLayers returns the URLs of the images composing the first crest matching selector found inside sel
+func crestLayers(sel *goquery.Selection, selector string) []string {
+	var layers []string
+	sel.Find(selector).First().Find("img").Each(func(i int, img *goquery.Selection) {
+		if src, found := img.Attr("src"); found {
+			layers = append(layers, src)
+		}
+	})
+
+	return layers
+}
+
+// fcSection returns the element following the free company heading with the given text
+func fcSection(doc *goquery.Document, heading string) *goquery.Selection {
+	return doc.Find(".heading--lead").FilterFunction(func(i int, sel *goquery.Selection) bool {
+		return strings.TrimSpace(sel.Text()) == heading
+	}).First().Next()
+}
+
+// FreeCompanyMember is a character listed in the member roster of a free company
+type FreeCompanyMember struct {
+	ID         int
+	Name       string
+	Avatar     string
+	World      string
+	DataCenter string
+	Region     string
+
+	Rank     string
+	RankIcon string
+}
+
+// FreeCompanyMembers returns the full member roster of a free company given its ID
+func (api *FFXIVAPI) FreeCompanyMembers(id string) ([]FreeCompanyMember, error) {
+	return api.FreeCompanyMembersContext(context.Background(), id)
+}
+
+// FreeCompanyMembersContext behaves like FreeCompanyMembers, aborting every Lodestone request if ctx is done
+// If any of the roster pages cannot be retrieved, a FeatureError is returned and the roster is discarded
+func (api *FFXIVAPI) FreeCompanyMembersContext(ctx context.Context, id string) ([]FreeCompanyMember, error) {
+	query := fmt.Sprintf("/lodestone/freecompany/%s/member/", id)
+	doc, err := api.lodestone(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pageErr error
+	members := make([]FreeCompanyMember, 0, 50)
+
+	api.eachPage(ctx, query, nil, doc, func(page int, doc *goquery.Document, err error) {
+		if err != nil {
+			pageErr = &FeatureError{Feature: FeatureNameMembers, Page: page, Err: err}
+			return
+		}
+
+		members = append(members, parseFreeCompanyMemberPage(doc)...)
+	})
+
+	if pageErr != nil {
+		return nil, pageErr
+	}
+
+	return members, nil
+}
+
+// parseFreeCompanyMemberPage returns the list of members found in a page of a free company roster
+func parseFreeCompanyMemberPage(doc *goquery.Document) []FreeCompanyMember {
+	members := make([]FreeCompanyMember, 0, 50)
+	doc.Find("li.entry > a.entry__bg").Each(func(i int, sel *goquery.Selection) {
+		matches := urlIdRegex.FindStringSubmatch(sel.AttrOr("href", ""))
+		if len(matches) < 2 {
+			return
+		}
+
+		rank := sel.Find(".entry__freecompany__info > li").First()
+		world := splitWorld(sel.Find(".entry__world").First().Text())
+		members = append(members, FreeCompanyMember{
+			ID:         silentAtoi(matches[1]),
+			Name:       strings.TrimSpace(sel.Find(".entry__name").First().Text()),
+			Avatar:     sel.Find(".entry__chara__face > img").First().AttrOr("src", ""),
+			World:      world.Name,
+			DataCenter: world.DataCenter,
+			Region:     world.Region,
+			Rank:       strings.TrimSpace(rank.Find("span").First().Text()),
+			RankIcon:   rank.Find("img").First().AttrOr("src", ""),
+		})
+	})
+
+	return members
+}
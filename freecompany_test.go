@@ -0,0 +1,140 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+	"time"
+)
+
+func TestFreeCompany(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/freecompany/9237023573225362244/": "freecompany.html",
+	}}
+
+	expected := &FreeCompany{
+		ID:         "9237023573225362244",
+		World:      "Ragnarok",
+		DataCenter: "Chaos",
+		Region:     GameRegionEU,
+		Name:       "Fun Company",
+		Tag:        "FUN",
+		Slogan:     "Come for the maps, stay for the fun.",
+		Crest: []string{
+			"https://img2.finalfantasyxiv.com/c/B0_0f9b1c6c0f0e6a8f0e8a7a2f5a8c4d3e_00_64x64.png",
+			"https://img2.finalfantasyxiv.com/c/F0_8e3d59a6a7e1a0b3f2c1d6e5b4a39281_02_64x64.png",
+			"https://img2.finalfantasyxiv.com/c/S7c_4b8f6a3c2e1d0f9e8d7c6b5a49382716_07_64x64.png",
+		},
+		Formed:        time.Unix(1379512345, 0),
+		Rank:          30,
+		ActiveMembers: 42,
+		Active:        "Always",
+		Recruiting:    true,
+		Focus: []FreeCompanyFocus{
+			{Name: "Role-playing", Icon: "https://img.finalfantasyxiv.com/lds/h/R/roleplay.png", Active: true},
+			{Name: "Leveling", Icon: "https://img.finalfantasyxiv.com/lds/h/L/leveling.png"},
+		},
+		Reputation: []FreeCompanyReputation{
+			{GC: "Maelstrom", Standing: "Allied", Progress: 100},
+			{GC: "Order of the Twin Adder", Standing: "Friendly", Progress: 35},
+		},
+	}
+	expected.GC.Name = "Maelstrom"
+	expected.GC.Standing = "Allied"
+	expected.Estate.Name = "Fun House"
+	expected.Estate.Address = "Plot 12, 5 Ward, Mist (Medium)"
+	expected.Estate.Greeting = "Welcome home!"
+
+	for _, tc := range []struct {
+		name     string
+		id       string
+		expected *FreeCompany
+		err      error
+	}{
+		{name: "profile", id: "9237023573225362244", expected: expected},
+		{name: "missing", id: "1", err: lodestone.HTTPError(404)},
+	} {
+		fc, err := api.FreeCompanyContext(context.Background(), tc.id)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		fc.ParsedAt = time.Time{}
+		if !reflect.DeepEqual(fc, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, fc)
+		}
+	}
+}
+
+func TestFreeCompanyMembers(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/freecompany/1/member/":        "freecompany_member.html",
+		"/lodestone/freecompany/1/member/?page=2": "freecompany_member_2.html",
+		"/lodestone/freecompany/2/member/":        "freecompany_member.html",
+	}}
+
+	for _, tc := range []struct {
+		name     string
+		id       string
+		expected []FreeCompanyMember
+		err      error
+	}{
+		{
+			name: "every page",
+			id:   "1",
+			expected: []FreeCompanyMember{
+				{
+					ID: 31688528, Name: "Alyx Bergen", Avatar: "https://img2.finalfantasyxiv.com/f/alyx_96x96.jpg",
+					World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+					Rank: "Master", RankIcon: "https://img.finalfantasyxiv.com/lds/h/Z/master.png",
+				},
+				{
+					ID: 2, Name: "Lyse Hext", Avatar: "https://img2.finalfantasyxiv.com/f/lyse_96x96.jpg",
+					World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+					Rank: "Officer", RankIcon: "https://img.finalfantasyxiv.com/lds/h/3/officer.png",
+				},
+				{
+					ID: 3, Name: "Tataru Taru", Avatar: "https://img2.finalfantasyxiv.com/f/tataru_96x96.jpg",
+					World: "Omega", DataCenter: "Chaos", Region: GameRegionEU,
+					Rank: "Member", RankIcon: "https://img.finalfantasyxiv.com/lds/h/1/member.png",
+				},
+			},
+		},
+		{
+			name: "missing page",
+			id:   "2",
+			err:  &FeatureError{Feature: FeatureNameMembers, Page: 2, Err: lodestone.HTTPError(404)},
+		},
+		{
+			name: "missing company",
+			id:   "3",
+			err:  lodestone.HTTPError(404),
+		},
+	} {
+		members, err := api.FreeCompanyMembersContext(context.Background(), tc.id)
+		if tc.err != nil {
+			if err == nil || err.Error() != tc.err.Error() || members != nil {
+				t.Errorf("%s: expected %v, got %v and %v", tc.name, tc.err, members, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(members, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, members)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package ffxivapi
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"regexp"
+	"strings"
+)
+
+// Gear holds the items equipped by a character
+type Gear struct {
+	Items []GearItem
+	// AverageItemLevel is computed as the game does, counting two-handed weapons twice and ignoring the soul crystal
+	AverageItemLevel int
+}
+
+// GearItem is an item equipped in one of the gear slots of a character
+type GearItem struct {
+	Slot     string
+	Category string
+
+	Name      string
+	DBID      string
+	ItemLevel int
+	HQ        bool
+
+	Glamour string
+	Materia []string
+	Dye     string
+	Crafter string
+}
+
+// Gear slots, in the same order the lodestone lists them
+const (
+	SlotMainHand    = "MainHand"
+	SlotOffHand     = "OffHand"
+	SlotHead        = "Head"
+	SlotBody        = "Body"
+	SlotHands       = "Hands"
+	SlotLegs        = "Legs"
+	SlotFeet        = "Feet"
+	SlotEarrings    = "Earrings"
+	SlotNecklace    = "Necklace"
+	SlotBracelets   = "Bracelets"
+	SlotRing1       = "Ring1"
+	SlotRing2       = "Ring2"
+	SlotSoulCrystal = "SoulCrystal"
+)
+
+// gearSlots lists slots in the order their boxes appear in the profile page, including empty ones
+var gearSlots = []string{
+	SlotMainHand, SlotOffHand, SlotHead, SlotBody, SlotHands, SlotLegs, SlotFeet,
+	SlotEarrings, SlotNecklace, SlotBracelets, SlotRing1, SlotRing2, SlotSoulCrystal,
+}
+
+// itemLevelRegex obtains the number from item level labels, such as "Item Level 560"
+var itemLevelRegex = regexp.MustCompile(`(\d+)`)
+
+// dbItemRegex obtains the Lodestone DB ID from item detail links
+var dbItemRegex = regexp.MustCompile(`/db/item/([0-9a-f]+)/?`)
+
+// parseGear returns the gear equipped by the character whose profile page is doc
+func parseGear(doc *goquery.Document) *Gear {
+	gear := &Gear{}
+
+	doc.Find(".ic_reflection_box").Each(func(i int, box *goquery.Selection) {
+		if i >= len(gearSlots) {
+			return
+		}
+
+		// Empty slots still have a box, but no item details
+		sel := box.Find(".item_detail_box").First()
+		if sel.Length() == 0 {
+			return
+		}
+
+		item := GearItem{
+			Slot:     gearSlots[i],
+			Category: strings.TrimSpace(sel.Find(".db-tooltip__item__category").First().Text()),
+			Glamour:  strings.TrimSpace(sel.Find(".db-tooltip__item__mirage > p").First().Text()),
+			Dye:      strings.TrimSpace(sel.Find(".stain").First().Text()),
+			Crafter:  strings.TrimSpace(sel.Find(".db-tooltip__signature-character").First().Text()),
+		}
+
+		// HQ items have an icon next to their name
+		name := sel.Find(".db-tooltip__item__name").First()
+		item.Name = strings.TrimSpace(name.Text())
+		item.HQ = name.Find("img").Length() > 0
+
+		matches := itemLevelRegex.FindStringSubmatch(sel.Find(".db-tooltip__item__level").First().Text())
+		if len(matches) >= 2 {
+			item.ItemLevel = silentAtoi(matches[1])
+		}
+
+		matches = dbItemRegex.FindStringSubmatch(sel.Find(".db-tooltip__bt_item_detail > a").First().AttrOr("href", ""))
+		if len(matches) >= 2 {
+			item.DBID = matches[1]
+		}
+
+		// Materia text is followed by the stat it grants, which is enclosed in a span
+		sel.Find(".db-tooltip__materia__txt").Each(func(i int, materia *goquery.Selection) {
+			name := strings.TrimSpace(strings.TrimSuffix(materia.Text(), materia.Find("span").Text()))
+			if name != "" {
+				item.Materia = append(item.Materia, name)
+			}
+		})
+
+		gear.Items = append(gear.Items, item)
+	})
+
+	gear.AverageItemLevel = averageItemLevel(gear.Items)
+	return gear
+}
+
+// averageItemLevel computes the average item level of the given items over the 12 slots the game accounts for
+func averageItemLevel(items []GearItem) int {
+	total := 0
+	mainHand := 0
+	offHand := false
+
+	for _, item := range items {
+		switch item.Slot {
+		case SlotSoulCrystal:
+			continue
+		case SlotMainHand:
+			mainHand = item.ItemLevel
+		case SlotOffHand:
+			offHand = true
+		}
+
+		total += item.ItemLevel
+	}
+
+	// Two-handed weapons take the place of the off hand as well
+	if !offHand {
+		total += mainHand
+	}
+
+	return total / (len(gearSlots) - 1)
+}
@@ -0,0 +1,71 @@
+package ffxivapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGear(t *testing.T) {
+	expected := &Gear{
+		Items: []GearItem{
+			{
+				Slot: SlotMainHand, Category: "Two-handed Conjurer's Arm", Name: "Augmented Crystarium Cane",
+				DBID: "b9a5d5a5d3a", ItemLevel: 530, Glamour: "Curtana",
+				Materia: []string{"Savage Aim Materia VIII", "Savage Might Materia VIII"},
+			},
+			{
+				Slot: SlotHead, Category: "Head", Name: "Crystarium Hat of Healing", DBID: "3d6f1e2c4b7",
+				ItemLevel: 520, HQ: true, Materia: []string{"Heavens' Eye Materia VIII"}, Dye: "Jet Black",
+				Crafter: "Tataru Taru",
+			},
+			{Slot: SlotBody, Category: "Body", Name: "Edenmorn Robe of Healing", DBID: "6e4b7a2c1d9", ItemLevel: 510},
+			{Slot: SlotRing1, Category: "Ring", Name: "Edenmorn Ring of Healing", DBID: "8f2a5c3e7b1", ItemLevel: 500},
+			{Slot: SlotSoulCrystal, Category: "Soul Crystal", Name: "Soul of the White Mage", DBID: "0a1b2c3d4e5", ItemLevel: 30},
+		},
+		// The two-handed weapon counts twice, and the soul crystal is ignored
+		AverageItemLevel: (530*2 + 520 + 510 + 500) / 12,
+	}
+
+	if gear := parseGear(fixtureDocument(t, "character_gear.html")); !reflect.DeepEqual(gear, expected) {
+		t.Errorf("expected %+v, got %+v", expected, gear)
+	}
+
+	if gear := parseGear(fixtureDocument(t, "empty.html")); len(gear.Items) != 0 || gear.AverageItemLevel != 0 {
+		t.Errorf("expected no gear, got %+v", gear)
+	}
+}
+
+func TestAverageItemLevel(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		items    []GearItem
+		expected int
+	}{
+		{
+			name: "two-handed weapon",
+			items: []GearItem{
+				{Slot: SlotMainHand, ItemLevel: 600}, {Slot: SlotHead, ItemLevel: 600},
+			},
+			expected: 150,
+		},
+		{
+			name: "off hand",
+			items: []GearItem{
+				{Slot: SlotMainHand, ItemLevel: 600}, {Slot: SlotOffHand, ItemLevel: 0}, {Slot: SlotHead, ItemLevel: 600},
+			},
+			expected: 100,
+		},
+		{
+			name: "soul crystal",
+			items: []GearItem{
+				{Slot: SlotHead, ItemLevel: 120}, {Slot: SlotSoulCrystal, ItemLevel: 120},
+			},
+			expected: 10,
+		},
+		{name: "no gear"},
+	} {
+		if average := averageItemLevel(tc.items); average != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, average)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	ffxivapihttp "roob.re/ffxivapi/http"
 	"roob.re/ffxivapi/lodestone"
 	"roob.re/tcache"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -30,40 +31,139 @@ func main() {
 	}
 	log.SetLevel(loglvl)
 
-	region := "eu"
+	region := lodestone.RegionEU
 	if envRegion := os.Getenv("FFXIVAPI_REGION"); envRegion != "" {
+		if !lodestone.ValidRegion(envRegion) {
+			log.Fatal("FFXIVAPI_REGION must be one of eu, na, jp, fr or de")
+		}
+
 		region = envRegion
 	}
-	log.Infof("Using region %s", region)
+	log.Infof("Using default region %s", region)
 
-	server := lodestone.CanonServerFromRegion(region)
-	if envServer := os.Getenv("FFXIVAPI_SERVER"); envServer != "" {
-		if !strings.HasPrefix(envServer, "http") {
-			log.Fatal("FFXIVAPI_SERVER must start with http")
+	rate := float64(lodestone.DefaultRate)
+	if envRate := os.Getenv("FFXIVAPI_RATELIMIT"); envRate != "" {
+		var err error
+		rate, err = strconv.ParseFloat(envRate, 64)
+		if err != nil || rate < 0 {
+			log.Fatal("FFXIVAPI_RATELIMIT must be a non-negative number of requests per second")
+		}
+	}
+
+	maxInFlight := lodestone.DefaultMaxInFlight
+	if envMaxInFlight := os.Getenv("FFXIVAPI_MAXINFLIGHT"); envMaxInFlight != "" {
+		var err error
+		maxInFlight, err = strconv.Atoi(envMaxInFlight)
+		if err != nil || maxInFlight < 0 {
+			log.Fatal("FFXIVAPI_MAXINFLIGHT must be a non-negative number of requests")
 		}
+	}
+	log.Infof("Limiting lodestone requests to %g/s and %d in flight", rate, maxInFlight)
+
+	// A nil policy makes lodestone clients use the default, linear one
+	var retryPolicy lodestone.RetryPolicy
+	switch os.Getenv("FFXIVAPI_RETRY") {
+	case "", "linear":
+	case "exponential":
+		log.Info("Using exponential backoff for lodestone retries")
+		retryPolicy = lodestone.NewExponentialRetryPolicy()
+	default:
+		log.Fatal("FFXIVAPI_RETRY must be either linear or exponential")
+	}
 
-		server = envServer
+	// Rate limiting is applied below the cache, so cache hits are not throttled
+	var transport http.RoundTripper = &lodestone.RateLimitedRoundTripper{
+		RoundTripper: http.DefaultTransport,
+		Limiter:      lodestone.NewRateLimiter(rate, lodestone.DefaultBurst, maxInFlight),
 	}
-	log.Infof("Using lodestone server %s", server)
 
 	// If FFXIVAPI_NOCACHE does not exist (== "")
-	var client = http.DefaultClient
 	if os.Getenv("FFXIVAPI_NOCACHE") == "" {
-		log.Info("Using tcache-based caching client")
-
-		client = &http.Client{
-			Transport: &lodestone.TCacheRoundTripper{
-				RoundTripper: http.DefaultTransport,
-				Cache:        tcache.New(tcache.NewMemStorage()),
-				MaxAge:       15 * time.Minute,
-			},
+		var maxStale time.Duration
+		if envMaxStale := os.Getenv("FFXIVAPI_CACHE_MAXSTALE"); envMaxStale != "" {
+			var err error
+			maxStale, err = time.ParseDuration(envMaxStale)
+			if err != nil || maxStale < 0 {
+				log.Fatal("FFXIVAPI_CACHE_MAXSTALE must be a non-negative duration, such as 72h")
+			}
+		}
+		log.Infof("Using tcache-based caching client, serving stale responses for %s", maxStale)
+
+		// Custom rules take precedence over the default ones
+		policy := lodestone.DefaultCachePolicy
+		if envPolicy := os.Getenv("FFXIVAPI_CACHE_POLICY"); envPolicy != "" {
+			custom, err := lodestone.ParseCachePolicy(envPolicy)
+			if err != nil {
+				log.Fatalf("FFXIVAPI_CACHE_POLICY: %v", err)
+			}
+
+			policy = append(custom, policy...)
+		}
+
+		var cache lodestone.Cache = tcache.New(tcache.NewMemStorage())
+		if cacheDir := os.Getenv("FFXIVAPI_CACHE_DIR"); cacheDir != "" {
+			maxSize := int64(256)
+			if envMaxSize := os.Getenv("FFXIVAPI_CACHE_MAXSIZE"); envMaxSize != "" {
+				var err error
+				maxSize, err = strconv.ParseInt(envMaxSize, 10, 64)
+				if err != nil || maxSize <= 0 {
+					log.Fatal("FFXIVAPI_CACHE_MAXSIZE must be a positive number of megabytes")
+				}
+			}
+
+			log.Infof("Using disk cache in %s, up to %dMB", cacheDir, maxSize)
+			diskCache, err := lodestone.NewDiskCache(cacheDir, maxSize<<20)
+			if err != nil {
+				log.Fatal(err)
+			}
+			cache = diskCache
+		}
+
+		// A redis server allows sharing the cache between replicas, so it takes precedence over the disk cache
+		if redisAddr := os.Getenv("FFXIVAPI_CACHE_REDIS"); redisAddr != "" {
+			log.Infof("Using redis cache at %s", redisAddr)
+			redisCache := lodestone.NewRedisCache(redisAddr, maxInFlight+8)
+			redisCache.Password = os.Getenv("FFXIVAPI_CACHE_REDIS_PASSWORD")
+			cache = redisCache
+		}
+
+		transport = &lodestone.TCacheRoundTripper{
+			RoundTripper: transport,
+			Cache:        cache,
+			MaxAge:       15 * time.Minute,
+			MaxStale:     maxStale,
+			Policy:       policy,
+		}
+	}
+	client := &http.Client{Transport: transport}
+
+	language := lodestone.RegionLanguage(region)
+	if envLanguage := os.Getenv("FFXIVAPI_LANGUAGE"); envLanguage != "" {
+		if !lodestone.ValidLanguage(envLanguage) {
+			log.Fatalf("FFXIVAPI_LANGUAGE must be one of en, ja, de or fr")
 		}
+
+		language = envLanguage
 	}
+	log.Infof("Using default language %s", language)
 
 	api := ffxivapi.New()
-	api.Lodestone = &lodestone.HTTPClient{
-		Server:     server,
-		HTTPClient: client,
+	api.Language = language
+	api.Lodestone = lodestone.NewMultiRegionClient(client, region, retryPolicy)
+
+	// A custom server replaces the whole lodestone, so requests cannot be routed by region
+	if envServer := os.Getenv("FFXIVAPI_SERVER"); envServer != "" {
+		if !strings.HasPrefix(envServer, "http") {
+			log.Fatal("FFXIVAPI_SERVER must start with http")
+		}
+
+		log.Infof("Using lodestone server %s", envServer)
+		api.Lodestone = &lodestone.HTTPClient{
+			Server:      envServer,
+			HTTPClient:  client,
+			Language:    language,
+			RetryPolicy: retryPolicy,
+		}
 	}
 
 	h := ffxivapihttp.NewWithApi(api)
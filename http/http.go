@@ -7,6 +7,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/swaggo/http-swagger"
 	"net/http"
+	"regexp"
 	"roob.re/ffxivapi"
 	"roob.re/ffxivapi/lodestone"
 	"strconv"
@@ -28,10 +29,23 @@ func NewWithApi(api *ffxivapi.FFXIVAPI) *Api {
 	}
 
 	h.Use(logRequest)
+	h.Use(requestLanguage)
+	h.Use(requestRegion)
 	h.Handle("/", http.RedirectHandler("/doc/", http.StatusMovedPermanently))
 	h.HandleFunc("/character/search", h.search)
 	h.HandleFunc("/character/{id}", h.character)
 	h.HandleFunc("/character/{id}/avatar", h.characterAvatar)
+	h.HandleFunc("/character/{id}/mounts", h.characterMounts)
+	h.HandleFunc("/character/{id}/minions", h.characterMinions)
+	h.HandleFunc("/freecompany/search", h.freeCompanySearch)
+	h.HandleFunc("/freecompany/{id}", h.freeCompany)
+	h.HandleFunc("/freecompany/{id}/members", h.freeCompanyMembers)
+	h.HandleFunc("/linkshell/search", h.linkshellSearch)
+	h.HandleFunc("/linkshell/{id}", h.linkshell)
+	h.HandleFunc("/crossworldlinkshell/search", h.crossWorldLinkshellSearch)
+	h.HandleFunc("/crossworldlinkshell/{id}", h.crossWorldLinkshell)
+	h.HandleFunc("/pvpteam/{id}", h.pvpTeam)
+	h.HandleFunc("/stats", h.stats)
 
 	h.Handle("/swagger.yaml", http.FileServer(http.Dir("http")))
 	h.PathPrefix("/doc").Handler(httpSwagger.Handler(httpSwagger.URL("/swagger.yaml")))
@@ -41,27 +55,50 @@ func NewWithApi(api *ffxivapi.FFXIVAPI) *Api {
 
 func (h *Api) search(rw http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
-	world := r.FormValue("world")
-	if name == "" || world == "" {
+	options := ffxivapi.SearchOptions{
+		World:      r.FormValue("world"),
+		DataCenter: r.FormValue("dc"),
+		ClassJob:   r.FormValue("classjob"),
+		Race:       r.FormValue("race"),
+		Tribe:      r.FormValue("tribe"),
+		GC:         r.FormValue("gc"),
+		Language:   r.FormValue("lang"),
+		Order:      r.FormValue("order"),
+		Page:       1,
+	}
+	if name == "" || (options.World == "" && options.DataCenter == "") {
 		rw.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	results, err := h.xivapi.Search(name, world)
+	if r.FormValue("all") != "" {
+		options.Page = ffxivapi.SearchAllPages
+	} else if pageStr := r.FormValue("page"); pageStr != "" {
+		var err error
+		options.Page, err = strconv.Atoi(pageStr)
+		if err != nil || options.Page < 1 {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.xivapi.SearchWithOptionsContext(r.Context(), name, options)
 	if err != nil {
-		rw.WriteHeader(http.StatusBadGateway)
-		rw.Write([]byte(err.Error()))
+		searchError(rw, err)
 		return
 	}
 
-	if len(results) == 0 {
+	// Totals are sent as headers so the body is kept as a plain list of results
+	rw.Header().Add("content-type", "application/json")
+	rw.Header().Add("x-total-count", strconv.Itoa(results.Total))
+	rw.Header().Add("x-total-pages", strconv.Itoa(results.Pages))
+
+	if len(results.Results) == 0 {
 		rw.WriteHeader(http.StatusNotFound)
 	}
 
-	rw.Header().Add("content-type", "application/json")
-
 	je := json.NewEncoder(rw)
-	je.Encode(results)
+	je.Encode(results.Results)
 }
 
 func (h *Api) character(rw http.ResponseWriter, r *http.Request) {
@@ -79,22 +116,35 @@ func (h *Api) character(rw http.ResponseWriter, r *http.Request) {
 	if r.FormValue("classjob") != "" {
 		features |= ffxivapi.FeatureClassJob
 	}
+	if r.FormValue("gear") != "" {
+		features |= ffxivapi.FeatureGear
+	}
+	if r.FormValue("mounts") != "" {
+		features |= ffxivapi.FeatureMounts
+	}
+	if r.FormValue("minions") != "" {
+		features |= ffxivapi.FeatureMinions
+	}
 
-	character, err := h.xivapi.Character(id, features)
-	var herr lodestone.HTTPError
-	if errors.As(err, &herr) && herr == http.StatusNotFound {
-		rw.WriteHeader(http.StatusNotFound)
-		return
-	} else if err != nil {
-		rw.WriteHeader(http.StatusBadGateway)
-		rw.Write([]byte(err.Error()))
+	character, err := h.xivapi.CharacterContext(r.Context(), id, features)
+	if err != nil {
+		lodestoneError(rw, err)
 		return
 	}
 
 	rw.Header().Add("content-type", "application/json")
 
 	je := json.NewEncoder(rw)
-	je.Encode(character)
+	je.Encode(characterResponse{
+		Character: character,
+		Partial:   character.Partial(),
+	})
+}
+
+// characterResponse adds a flag to the character JSON signaling whether some of the requested features are incomplete
+type characterResponse struct {
+	*ffxivapi.Character
+	Partial bool
 }
 
 func (h *Api) characterAvatar(rw http.ResponseWriter, r *http.Request) {
@@ -105,18 +155,278 @@ func (h *Api) characterAvatar(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	character, err := h.xivapi.Character(id, 0)
-	var herr lodestone.HTTPError
-	if errors.As(err, &herr) && herr == http.StatusNotFound {
+	character, err := h.xivapi.CharacterContext(r.Context(), id, 0)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	// Avatars rarely change, so clients can reuse the redirection for as long as the profile is cached
+	rw.Header().Add("cache-control", "public, max-age=1800")
+	http.Redirect(rw, r, character.Avatar, http.StatusFound)
+}
+
+func (h *Api) characterMounts(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	mounts, err := h.xivapi.MountsContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(mounts)
+}
+
+func (h *Api) characterMinions(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	minions, err := h.xivapi.MinionsContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(minions)
+}
+
+func (h *Api) freeCompanySearch(rw http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	world := r.FormValue("world")
+	if name == "" || world == "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filters := ffxivapi.FreeCompanySearchFilters{
+		GC:          r.FormValue("gc"),
+		ActiveTime:  r.FormValue("activetime"),
+		Recruitment: r.FormValue("recruitment"),
+		House:       r.FormValue("house"),
+		Focus:       r.FormValue("focus"),
+		Members:     r.FormValue("members"),
+	}
+
+	results, err := h.xivapi.SearchFreeCompanyContext(r.Context(), name, world, filters)
+	if err != nil {
+		searchError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	if len(results) == 0 {
+		rw.WriteHeader(http.StatusNotFound)
+	}
+
+	je := json.NewEncoder(rw)
+	je.Encode(results)
+}
+
+func (h *Api) freeCompany(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// FC IDs do not fit in an int64, so they are only validated as numbers and handled as strings
+	id := vars["id"]
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fc, err := h.xivapi.FreeCompanyContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(fc)
+}
+
+func (h *Api) freeCompanyMembers(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.xivapi.FreeCompanyMembersContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(members)
+}
+
+func (h *Api) linkshellSearch(rw http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	world := r.FormValue("world")
+	if name == "" || world == "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.xivapi.SearchLinkshellContext(r.Context(), name, world)
+	if err != nil {
+		searchError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	if len(results) == 0 {
 		rw.WriteHeader(http.StatusNotFound)
+	}
+
+	je := json.NewEncoder(rw)
+	je.Encode(results)
+}
+
+func (h *Api) linkshell(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ls, err := h.xivapi.LinkshellContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(ls)
+}
+
+func (h *Api) crossWorldLinkshellSearch(rw http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	dc := r.FormValue("dc")
+	if name == "" || dc == "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.xivapi.SearchCrossWorldLinkshellContext(r.Context(), name, dc)
+	if err != nil {
+		searchError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	if len(results) == 0 {
+		rw.WriteHeader(http.StatusNotFound)
+	}
+
+	je := json.NewEncoder(rw)
+	je.Encode(results)
+}
+
+// hexIDRegex matches cross-world linkshell and PvP team IDs, which are hex strings rather than numbers
+var hexIDRegex = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+func (h *Api) crossWorldLinkshell(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !hexIDRegex.MatchString(id) {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ls, err := h.xivapi.CrossWorldLinkshellContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(ls)
+}
+
+func (h *Api) pvpTeam(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !hexIDRegex.MatchString(id) {
+		rw.WriteHeader(http.StatusBadRequest)
 		return
-	} else if err != nil {
-		rw.WriteHeader(http.StatusBadGateway)
+	}
+
+	team, err := h.xivapi.PvPTeamContext(r.Context(), id)
+	if err != nil {
+		lodestoneError(rw, err)
+		return
+	}
+
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(team)
+}
+
+// statsResponse holds the runtime statistics of the api
+type statsResponse struct {
+	Coalescing lodestone.GroupStats
+}
+
+func (h *Api) stats(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Add("content-type", "application/json")
+
+	je := json.NewEncoder(rw)
+	je.Encode(statsResponse{
+		Coalescing: h.xivapi.CoalescingStats(),
+	})
+}
+
+// searchError writes to rw the status code corresponding to an error returned by a search, which can be caused by
+// invalid search parameters as well as by the lodestone
+func searchError(rw http.ResponseWriter, err error) {
+	if errors.Is(err, ffxivapi.ErrInvalidFilter) || errors.Is(err, ffxivapi.ErrUnknownWorld) {
+		rw.WriteHeader(http.StatusBadRequest)
 		rw.Write([]byte(err.Error()))
 		return
 	}
 
-	http.Redirect(rw, r, character.Avatar, http.StatusFound)
+	lodestoneError(rw, err)
+}
+
+// lodestoneError writes to rw the status code corresponding to an error returned while querying the lodestone
+func lodestoneError(rw http.ResponseWriter, err error) {
+	var herr lodestone.HTTPError
+	if errors.As(err, &herr) && herr == http.StatusNotFound {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	rw.WriteHeader(http.StatusBadGateway)
+	rw.Write([]byte(err.Error()))
 }
 
 func logRequest(handler http.Handler) http.Handler {
@@ -125,3 +435,47 @@ func logRequest(handler http.Handler) http.Handler {
 		handler.ServeHTTP(writer, request)
 	})
 }
+
+// requestLanguage makes lodestone requests use the language given in the locale parameter, if any
+func requestLanguage(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		lang := request.FormValue("locale")
+		if lang == "" {
+			handler.ServeHTTP(writer, request)
+			return
+		}
+
+		if !lodestone.ValidLanguage(lang) {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte("unknown locale " + lang))
+			return
+		}
+
+		handler.ServeHTTP(writer, request.WithContext(lodestone.WithLanguage(request.Context(), lang)))
+	})
+}
+
+// requestRegion makes lodestone requests be routed to the region given in the region parameter, if any. Both lodestone
+// and game regions are accepted, in any case
+func requestRegion(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		region := request.FormValue("region")
+		if region == "" {
+			handler.ServeHTTP(writer, request)
+			return
+		}
+
+		// Game regions are mapped to the lodestone region serving them, so the Region of characters can be used as is
+		if lodestoneRegion, found := ffxivapi.LodestoneRegion(region); found {
+			region = lodestoneRegion
+		}
+
+		if !lodestone.ValidRegion(region) {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte("unknown region " + region))
+			return
+		}
+
+		handler.ServeHTTP(writer, request.WithContext(lodestone.WithRegion(request.Context(), region)))
+	})
+}
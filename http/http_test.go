@@ -0,0 +1,99 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"roob.re/ffxivapi"
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+)
+
+// fixtureLodestone is a plain lodestone.Client answering each query with the testdata file of the ffxivapi package it is
+// mapped to, and any other query with a 404 error
+type fixtureLodestone map[string]string
+
+func (fl fixtureLodestone) Request(query string) (io.ReadCloser, error) {
+	name, found := fl[query]
+	if !found {
+		return nil, lodestone.HTTPError(http.StatusNotFound)
+	}
+
+	return os.Open(filepath.Join("..", "testdata", name))
+}
+
+func TestSearch(t *testing.T) {
+	api := NewWithApi(&ffxivapi.FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/character/?q=alyx&worldname=Ragnarok":        "character_search.html",
+		"/lodestone/character/?page=2&q=alyx&worldname=Ragnarok": "character_search_2.html",
+		"/lodestone/character/?page=3&q=alyx&worldname=Ragnarok": "character_search_3.html",
+		"/lodestone/character/?q=nobody&worldname=Ragnarok":      "empty.html",
+	}})
+
+	for _, tc := range []struct {
+		name       string
+		url        string
+		status     int
+		totalCount string
+		totalPages string
+		ids        []int
+	}{
+		{
+			name: "first page", url: "/character/search?name=alyx&world=Ragnarok",
+			status: http.StatusOK, totalCount: "5", totalPages: "3", ids: []int{31688528, 2},
+		},
+		{
+			name: "second page", url: "/character/search?name=alyx&world=Ragnarok&page=2",
+			status: http.StatusOK, totalCount: "5", totalPages: "3", ids: []int{3, 4},
+		},
+		{
+			name: "all pages", url: "/character/search?name=alyx&world=Ragnarok&all=1",
+			status: http.StatusOK, totalCount: "5", totalPages: "3", ids: []int{31688528, 2, 3, 4, 5},
+		},
+		{
+			name: "no results", url: "/character/search?name=nobody&world=Ragnarok",
+			status: http.StatusNotFound, totalCount: "0", totalPages: "1", ids: []int{},
+		},
+		{name: "invalid page", url: "/character/search?name=alyx&world=Ragnarok&page=0", status: http.StatusBadRequest},
+		{name: "missing page", url: "/character/search?name=alyx&world=Ragnarok&page=4", status: http.StatusNotFound},
+	} {
+		rw := httptest.NewRecorder()
+		api.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+		if rw.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, rw.Code)
+			continue
+		}
+		if tc.ids == nil {
+			continue
+		}
+
+		if contentType := rw.Header().Get("content-type"); contentType != "application/json" {
+			t.Errorf("%s: expected json content type, got %q", tc.name, contentType)
+		}
+		if count := rw.Header().Get("x-total-count"); count != tc.totalCount {
+			t.Errorf("%s: expected x-total-count %s, got %q", tc.name, tc.totalCount, count)
+		}
+		if pages := rw.Header().Get("x-total-pages"); pages != tc.totalPages {
+			t.Errorf("%s: expected x-total-pages %s, got %q", tc.name, tc.totalPages, pages)
+		}
+
+		var results []ffxivapi.SearchResult
+		if err := json.NewDecoder(rw.Body).Decode(&results); err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		ids := make([]int, 0, len(results))
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.ids, ids)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package ffxivapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"time"
+)
+
+// Linkshell models FFXIV linkshell and cross-world linkshell data
+type Linkshell struct {
+	ParsedAt time.Time
+
+	ID         string
+	Name       string
+	CrossWorld bool
+
+	// World is only set for linkshells, which are bound to a world, while cross-world linkshells only have a data center
+	World      string
+	DataCenter string
+	Region     string
+
+	Members []LinkshellMember
+}
+
+// LinkshellMember is a character listed in the member list of a linkshell
+type LinkshellMember struct {
+	ID         int
+	Name       string
+	Avatar     string
+	World      string
+	DataCenter string
+	Region     string
+	// Rank is the linkshell rank of the member, such as "Master" or "Leader", and empty for regular members
+	Rank string
+}
+
+// LinkshellSearchResult is a linkshell or cross-world linkshell as listed in the Lodestone search results
+type LinkshellSearchResult struct {
+	ID         string
+	Name       string
+	CrossWorld bool
+
+	// World is only set for linkshells, as in Linkshell
+	World      string
+	DataCenter string
+	Region     string
+
+	ActiveMembers int
+}
+
+// Linkshell returns linkshell data, including its full member list, given its ID
+func (api *FFXIVAPI) Linkshell(id string) (*Linkshell, error) {
+	return api.LinkshellContext(context.Background(), id)
+}
+
+// LinkshellContext behaves like Linkshell, aborting every Lodestone request if ctx is done
+func (api *FFXIVAPI) LinkshellContext(ctx context.Context, id string) (*Linkshell, error) {
+	return api.linkshell(ctx, fmt.Sprintf("/lodestone/linkshell/%s/", id), id, false)
+}
+
+// CrossWorldLinkshell returns cross-world linkshell data, including its full member list, given its ID
+func (api *FFXIVAPI) CrossWorldLinkshell(id string) (*Linkshell, error) {
+	return api.CrossWorldLinkshellContext(context.Background(), id)
+}
+
+// CrossWorldLinkshellContext behaves like CrossWorldLinkshell, aborting every Lodestone request if ctx is done
+func (api *FFXIVAPI) CrossWorldLinkshellContext(ctx context.Context, id string) (*Linkshell, error) {
+	return api.linkshell(ctx, fmt.Sprintf("/lodestone/crossworld_linkshell/%s/", id), id, true)
+}
+
+// linkshell parses the paginated member list of both kinds of linkshells, which also contains their profile
+func (api *FFXIVAPI) linkshell(ctx context.Context, query string, id string, crossWorld bool) (*Linkshell, error) {
+	doc, err := api.lodestone(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ls := &Linkshell{ID: id, CrossWorld: crossWorld, ParsedAt: time.Now()}
+	ls.Name = strings.TrimSpace(doc.Find(".heading__linkshell__name").First().Text())
+	if crossWorld {
+		ls.DataCenter = strings.TrimSpace(doc.Find(".heading__cwls__dcname").First().Text())
+		ls.Region = DataCenterRegion(ls.DataCenter)
+	}
+
+	var pageErr error
+	api.eachPage(ctx, query, nil, doc, func(page int, doc *goquery.Document, err error) {
+		if err != nil {
+			pageErr = &FeatureError{Feature: FeatureNameMembers, Page: page, Err: err}
+			return
+		}
+
+		ls.Members = append(ls.Members, parseLinkshellMemberPage(doc)...)
+	})
+
+	if pageErr != nil {
+		return nil, pageErr
+	}
+
+	// Linkshells are bound to a world, which is the one of any of its members
+	if !crossWorld && len(ls.Members) > 0 {
+		ls.World = ls.Members[0].World
+		ls.DataCenter = ls.Members[0].DataCenter
+		ls.Region = ls.Members[0].Region
+	}
+
+	return ls, nil
+}
+
+// parseLinkshellMemberPage returns the list of members found in a page of a linkshell member list
+func parseLinkshellMemberPage(doc *goquery.Document) []LinkshellMember {
+	members := make([]LinkshellMember, 0, 50)
+	doc.Find("li.entry > a.entry__bg").Each(func(i int, sel *goquery.Selection) {
+		matches := urlIdRegex.FindStringSubmatch(sel.AttrOr("href", ""))
+		if len(matches) < 2 {
+			return
+		}
+
+		world := splitWorld(sel.Find(".entry__world").First().Text())
+		members = append(members, LinkshellMember{
+			ID:         silentAtoi(matches[1]),
+			Name:       strings.TrimSpace(sel.Find(".entry__name").First().Text()),
+			Avatar:     sel.Find(".entry__chara__face > img").First().AttrOr("src", ""),
+			World:      world.Name,
+			DataCenter: world.DataCenter,
+			Region:     world.Region,
+			Rank:       strings.TrimSpace(sel.Find(".entry__chara_info__linkshell > span").First().Text()),
+		})
+	})
+
+	return members
+}
+
+// SearchLinkshell looks for linkshells matching the given name in the given world
+func (api *FFXIVAPI) SearchLinkshell(name string, world string) ([]LinkshellSearchResult, error) {
+	return api.SearchLinkshellContext(context.Background(), name, world)
+}
+
+// SearchLinkshellContext behaves like SearchLinkshell, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) SearchLinkshellContext(ctx context.Context, name string, world string) ([]LinkshellSearchResult, error) {
+	world, err := canonicalWorld(world)
+	if err != nil {
+		return nil, err
+	}
+
+	info, _ := LookupWorld(world)
+	ctx = api.withDataCenterRegion(ctx, info.DataCenter)
+
+	return api.searchLinkshell(ctx, "/lodestone/linkshell/", map[string]string{
+		"q":         name,
+		"worldname": world,
+	}, false)
+}
+
+// SearchCrossWorldLinkshell looks for cross-world linkshells matching the given name in the given data center
+func (api *FFXIVAPI) SearchCrossWorldLinkshell(name string, dataCenter string) ([]LinkshellSearchResult, error) {
+	return api.SearchCrossWorldLinkshellContext(context.Background(), name, dataCenter)
+}
+
+// SearchCrossWorldLinkshellContext behaves like SearchCrossWorldLinkshell, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) SearchCrossWorldLinkshellContext(ctx context.Context, name string, dataCenter string) ([]LinkshellSearchResult, error) {
+	dataCenter, err := canonicalDataCenter(dataCenter)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = api.withDataCenterRegion(ctx, dataCenter)
+
+	return api.searchLinkshell(ctx, "/lodestone/crossworld_linkshell/", map[string]string{
+		"q":      name,
+		"dcname": dataCenter,
+	}, true)
+}
+
+// searchLinkshell parses the search results for both kinds of linkshells, which share the same layout
+func (api *FFXIVAPI) searchLinkshell(ctx context.Context, query string, params map[string]string, crossWorld bool) ([]LinkshellSearchResult, error) {
+	doc, err := api.lodestone(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]LinkshellSearchResult, 0, 1)
+
+	doc.Find(".entry > a.entry__link--line").Each(func(i int, sel *goquery.Selection) {
+		// Cross-world linkshell IDs are not numeric, so they are taken verbatim from the last path component
+		parts := strings.Split(strings.Trim(sel.AttrOr("href", ""), "/"), "/")
+		if len(parts) == 0 {
+			return
+		}
+
+		result := LinkshellSearchResult{
+			ID:            parts[len(parts)-1],
+			Name:          strings.TrimSpace(sel.Find(".entry__name").First().Text()),
+			CrossWorld:    crossWorld,
+			ActiveMembers: silentAtoi(strings.TrimSpace(sel.Find(".entry__linkshell__num").First().Text())),
+		}
+
+		location := sel.Find(".entry__world").First().Text()
+		if crossWorld {
+			result.DataCenter = strings.TrimSpace(location)
+			result.Region = DataCenterRegion(result.DataCenter)
+		} else {
+			world := splitWorld(location)
+			result.World = world.Name
+			result.DataCenter = world.DataCenter
+			result.Region = world.Region
+		}
+
+		results = append(results, result)
+	})
+
+	return results, nil
+}
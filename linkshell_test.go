@@ -0,0 +1,178 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+	"time"
+)
+
+func TestLinkshell(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/linkshell/1/":        "linkshell.html",
+		"/lodestone/linkshell/1/?page=2": "linkshell_2.html",
+		"/lodestone/linkshell/2/":        "linkshell.html",
+		"/lodestone/crossworld_linkshell/a9c4b0e4f6f4b9a2d8e7c5a1b3f0e2d4c6a8b0e1/": "crossworld_linkshell.html",
+	}}
+
+	alyx := LinkshellMember{
+		ID: 31688528, Name: "Alyx Bergen", Avatar: "https://img2.finalfantasyxiv.com/f/alyx_96x96.jpg",
+		World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+		Rank: "Master",
+	}
+
+	for _, tc := range []struct {
+		name       string
+		id         string
+		crossWorld bool
+		expected   *Linkshell
+		err        error
+	}{
+		{
+			name: "linkshell",
+			id:   "1",
+			expected: &Linkshell{
+				ID:         "1",
+				Name:       "Fun Shell",
+				World:      "Ragnarok",
+				DataCenter: "Chaos",
+				Region:     GameRegionEU,
+				Members: []LinkshellMember{
+					alyx,
+					{
+						ID: 2, Name: "Lyse Hext", Avatar: "https://img2.finalfantasyxiv.com/f/lyse_96x96.jpg",
+						World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+						Rank: "Leader",
+					},
+					{
+						ID: 3, Name: "Tataru Taru", Avatar: "https://img2.finalfantasyxiv.com/f/tataru_96x96.jpg",
+						World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+					},
+				},
+			},
+		},
+		{
+			name:       "cross-world linkshell",
+			id:         "a9c4b0e4f6f4b9a2d8e7c5a1b3f0e2d4c6a8b0e1",
+			crossWorld: true,
+			expected: &Linkshell{
+				ID:         "a9c4b0e4f6f4b9a2d8e7c5a1b3f0e2d4c6a8b0e1",
+				Name:       "Fun Crossworld",
+				CrossWorld: true,
+				DataCenter: "Chaos",
+				Region:     GameRegionEU,
+				Members: []LinkshellMember{
+					alyx,
+					{
+						ID: 4, Name: "Krile Baldesion", Avatar: "https://img2.finalfantasyxiv.com/f/krile_96x96.jpg",
+						World: "Omega", DataCenter: "Chaos", Region: GameRegionEU,
+					},
+				},
+			},
+		},
+		{
+			name: "missing page",
+			id:   "2",
+			err:  &FeatureError{Feature: FeatureNameMembers, Page: 2, Err: lodestone.HTTPError(404)},
+		},
+		{
+			name:       "missing linkshell",
+			id:         "1",
+			crossWorld: true,
+			err:        lodestone.HTTPError(404),
+		},
+	} {
+		var ls *Linkshell
+		var err error
+		if tc.crossWorld {
+			ls, err = api.CrossWorldLinkshellContext(context.Background(), tc.id)
+		} else {
+			ls, err = api.LinkshellContext(context.Background(), tc.id)
+		}
+
+		if tc.err != nil {
+			if err == nil || err.Error() != tc.err.Error() || ls != nil {
+				t.Errorf("%s: expected %v, got %+v and %v", tc.name, tc.err, ls, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		ls.ParsedAt = time.Time{}
+		if !reflect.DeepEqual(ls, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, ls)
+		}
+	}
+}
+
+func TestSearchLinkshell(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/linkshell/?q=fun&worldname=Ragnarok":      "linkshell_search.html",
+		"/lodestone/crossworld_linkshell/?dcname=Chaos&q=fun": "crossworld_linkshell_search.html",
+		"/lodestone/linkshell/?q=nobody&worldname=Ragnarok":   "empty.html",
+	}}
+
+	for _, tc := range []struct {
+		name       string
+		lsName     string
+		location   string
+		crossWorld bool
+		expected   []LinkshellSearchResult
+		err        error
+	}{
+		{
+			name: "linkshells", lsName: "fun", location: "ragnarok",
+			expected: []LinkshellSearchResult{
+				{
+					ID: "19984723346535274", Name: "Fun Shell", ActiveMembers: 3,
+					World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+				},
+				{
+					ID: "19984723346535999", Name: "Fun Run", ActiveMembers: 12,
+					World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+				},
+			},
+		},
+		{
+			name: "cross-world linkshells", lsName: "fun", location: "chaos", crossWorld: true,
+			expected: []LinkshellSearchResult{
+				{
+					ID: "a9c4b0e4f6f4b9a2d8e7c5a1b3f0e2d4c6a8b0e1", Name: "Fun Crossworld", CrossWorld: true,
+					DataCenter: "Chaos", Region: GameRegionEU, ActiveMembers: 2,
+				},
+			},
+		},
+		{name: "no results", lsName: "nobody", location: "Ragnarok", expected: []LinkshellSearchResult{}},
+		{name: "unknown world", lsName: "fun", location: "Chaos", err: ErrUnknownWorld},
+		{name: "unknown data center", lsName: "fun", location: "Ragnarok", crossWorld: true, err: ErrUnknownWorld},
+	} {
+		var results []LinkshellSearchResult
+		var err error
+		if tc.crossWorld {
+			results, err = api.SearchCrossWorldLinkshellContext(context.Background(), tc.lsName, tc.location)
+		} else {
+			results, err = api.SearchLinkshellContext(context.Background(), tc.lsName, tc.location)
+		}
+
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v and %v", tc.name, tc.err, results, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(results, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, results)
+		}
+	}
+}
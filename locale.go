@@ -0,0 +1,489 @@
+package ffxivapi
+
+import (
+	"context"
+	"regexp"
+	"roob.re/ffxivapi/lodestone"
+	"strings"
+)
+
+// locale holds the language-dependent strings needed to parse lodestone pages in a given language
+type locale struct {
+	// achievementRegex obtains the achievement name from the flavour text of the achievement list
+	achievementRegex *regexp.Regexp
+	// privateRegex matches the notice displayed instead of the achievement list when it is private
+	privateRegex *regexp.Regexp
+	// disciplines maps the role headings of the class_job page to the discipline they belong to, which is always
+	// reported in English so classes can be grouped regardless of the language
+	disciplines map[string]string
+	// classJobs maps english class and job names to their localized version
+	classJobs map[string]string
+	// attributes maps the localized attribute labels to their english version
+	attributes map[string]string
+	// races and clans map the localized names of races and clans to their english version
+	races map[string]Race
+	clans map[string]Clan
+	// deities holds the localized names of the guardians, in the same order as the guardians table. Languages where
+	// they are spelled as in english leave it empty
+	deities []string
+	// namedayRegex captures the sun, moon and phase of namedays, the latter being umbral if it matches umbral
+	namedayRegex *regexp.Regexp
+	umbral       string
+	// fcHeadings holds the headings preceding the fields of the free company page
+	fcHeadings fcHeadings
+	// recruitmentOpen is the status displayed by free companies which are recruiting
+	recruitmentOpen string
+}
+
+// fcHeadings holds the localized headings of the free company page
+type fcHeadings struct {
+	formed        string
+	activeMembers string
+	rank          string
+	active        string
+}
+
+// Disciplines of the classes and jobs
+const (
+	DisciplineWar   = "Disciple of War"
+	DisciplineMagic = "Disciple of Magic"
+	DisciplineHand  = "Disciple of the Hand"
+	DisciplineLand  = "Disciple of the Land"
+)
+
+// classJob returns the localized name of a class or job given its english one
+func (l *locale) classJob(name string) string {
+	if localized, found := l.classJobs[name]; found {
+		return localized
+	}
+	return name
+}
+
+// attribute returns the english label of an attribute given its localized one
+func (l *locale) attribute(label string) string {
+	if english, found := l.attributes[label]; found {
+		return english
+	}
+	return label
+}
+
+// race returns the race named by its localized name, or the name itself if it is not known
+func (l *locale) race(name string) Race {
+	if race, found := l.races[name]; found {
+		return race
+	}
+	return Race(name)
+}
+
+// clan returns the clan named by its localized name, or the name itself if it is not known
+func (l *locale) clan(name string) Clan {
+	if clan, found := l.clans[name]; found {
+		return clan
+	}
+	return Clan(name)
+}
+
+// guardian returns the guardian whose name appears in the localized text displayed by the lodestone, which usually
+// includes a localized epithet, or the text itself if none does
+func (l *locale) guardian(text string) Guardian {
+	for i, guardian := range guardians {
+		deity := guardian.Deity()
+		if len(l.deities) == len(guardians) {
+			deity = l.deities[i]
+		}
+		if strings.Contains(text, deity) {
+			return guardian
+		}
+	}
+	return Guardian(text)
+}
+
+// locales holds the locale of each of the lodestone languages
+var locales = map[string]*locale{
+	lodestone.LanguageEnglish: {
+		achievementRegex: regexp.MustCompile(`achievement "(.+)" earned`),
+		privateRegex:     regexp.MustCompile(`(?i)private`),
+		disciplines: map[string]string{
+			"Tank":                  DisciplineWar,
+			"Melee DPS":             DisciplineWar,
+			"Physical Ranged DPS":   DisciplineWar,
+			"Healer":                DisciplineMagic,
+			"Magical Ranged DPS":    DisciplineMagic,
+			"Disciples of the Hand": DisciplineHand,
+			"Disciples of the Land": DisciplineLand,
+		},
+		namedayRegex: eorzeanDateRegex,
+		umbral:       PhaseUmbral,
+		fcHeadings: fcHeadings{
+			formed:        "Formed",
+			activeMembers: "Active Members",
+			rank:          "Rank",
+			active:        "Active",
+		},
+		recruitmentOpen: "Open",
+	},
+	lodestone.LanguageJapanese: {
+		achievementRegex: regexp.MustCompile(`アチーブメント「(.+)」を達成した`),
+		privateRegex:     regexp.MustCompile(`非公開`),
+		disciplines: map[string]string{
+			"タンク":     DisciplineWar,
+			"近接物理DPS": DisciplineWar,
+			"遠隔物理DPS": DisciplineWar,
+			"ヒーラー":    DisciplineMagic,
+			"遠隔魔法DPS": DisciplineMagic,
+			"クラフター":   DisciplineHand,
+			"ギャザラー":   DisciplineLand,
+		},
+		classJobs: map[string]string{
+			"Gladiator":     "剣術士",
+			"Paladin":       "ナイト",
+			"Warrior":       "戦士",
+			"Marauder":      "斧術士",
+			"Dark Knight":   "暗黒騎士",
+			"Gunbreaker":    "ガンブレイカー",
+			"Pugilist":      "格闘士",
+			"Monk":          "モンク",
+			"Lancer":        "槍術士",
+			"Dragoon":       "竜騎士",
+			"Rogue":         "双剣士",
+			"Ninja":         "忍者",
+			"Samurai":       "侍",
+			"Conjurer":      "幻術士",
+			"White Mage":    "白魔道士",
+			"Scholar":       "学者",
+			"Astrologian":   "占星術師",
+			"Archer":        "弓術士",
+			"Bard":          "吟遊詩人",
+			"Machinist":     "機工士",
+			"Dancer":        "踊り子",
+			"Thaumaturge":   "呪術士",
+			"Black Mage":    "黒魔道士",
+			"Arcanist":      "巴術士",
+			"Summoner":      "召喚士",
+			"Red Mage":      "赤魔道士",
+			"Blue Mage":     "青魔道士",
+			"Carpenter":     "木工師",
+			"Blacksmith":    "鍛冶師",
+			"Armorer":       "甲冑師",
+			"Goldsmith":     "彫金師",
+			"Leatherworker": "革細工師",
+			"Weaver":        "裁縫師",
+			"Alchemist":     "錬金術師",
+			"Culinarian":    "調理師",
+			"Miner":         "採掘師",
+			"Botanist":      "園芸師",
+			"Fisher":        "漁師",
+		},
+		attributes: map[string]string{
+			"STR":      "Strength",
+			"DEX":      "Dexterity",
+			"VIT":      "Vitality",
+			"INT":      "Intelligence",
+			"MND":      "Mind",
+			"力":        "Strength",
+			"器用さ":      "Dexterity",
+			"耐久力":      "Vitality",
+			"知性":       "Intelligence",
+			"精神":       "Mind",
+			"クリティカル":   "Critical Hit Rate",
+			"意思力":      "Determination",
+			"ダイレクトヒット": "Direct Hit Rate",
+			"物理防御力":    "Defense",
+			"魔法防御力":    "Magic Defense",
+			"物理攻撃力":    "Attack Power",
+			"スキルスピード":  "Skill Speed",
+			"攻撃魔法威力":   "Attack Magic Potency",
+			"回復魔法威力":   "Healing Magic Potency",
+			"スペルスピード":  "Spell Speed",
+			"不屈":       "Tenacity",
+			"信仰":       "Piety",
+		},
+		races: map[string]Race{
+			"ヒューラン": RaceHyur,
+			"エレゼン":  RaceElezen,
+			"ララフェル": RaceLalafell,
+			"ミコッテ":  RaceMiqote,
+			"ルガディン": RaceRoegadyn,
+			"アウラ":   RaceAuRa,
+			"ロスガル":  RaceHrothgar,
+			"ヴィエラ":  RaceViera,
+		},
+		clans: map[string]Clan{
+			"ミッドランダー":  ClanMidlander,
+			"ハイランダー":   ClanHighlander,
+			"フォレスター":   ClanWildwood,
+			"シェーダー":    ClanDuskwight,
+			"プレーンフォーク": ClanPlainsfolk,
+			"デューンフォーク": ClanDunesfolk,
+			"サンシーカー":   ClanSeekerOfTheSun,
+			"ムーンキーパー":  ClanKeeperOfTheMoon,
+			"ゼーヴォルフ":   ClanSeaWolf,
+			"ローエンガルデ":  ClanHellsguard,
+			"アウラ・レン":   ClanRaen,
+			"アウラ・ゼラ":   ClanXaela,
+			"ヘリオン":     ClanHelions,
+			"ロスト":      ClanTheLost,
+			"ラヴァ・ヴィエラ": ClanRava,
+			"ヴィナ・ヴィエラ": ClanVeena,
+		},
+		deities: []string{
+			"ハルオーネ", "メネフィナ", "サリャク", "ニメーヤ", "リムレーン", "オシュオン",
+			"ビエルゴ", "ラールガー", "アーゼマ", "ナルザル", "ノフィカ", "アルジク",
+		},
+		namedayRegex: regexp.MustCompile(`^(?P<phase>星|霊)(?P<moon>\d+)月\s*(?P<sun>\d+)日$`),
+		umbral:       "霊",
+		fcHeadings: fcHeadings{
+			formed:        "結成",
+			activeMembers: "アクティブメンバー数",
+			rank:          "ランク",
+			active:        "活動時間",
+		},
+		recruitmentOpen: "募集中",
+	},
+	lodestone.LanguageGerman: {
+		achievementRegex: regexp.MustCompile(`Errungenschaft [„"](.+)[“"]`),
+		privateRegex:     regexp.MustCompile(`(?i)nicht öffentlich|privat`),
+		disciplines: map[string]string{
+			"Verteidiger":                   DisciplineWar,
+			"Nahkampf-Angreifer":            DisciplineWar,
+			"Physische Fernkampf-Angreifer": DisciplineWar,
+			"Heiler":                        DisciplineMagic,
+			"Magische Fernkampf-Angreifer":  DisciplineMagic,
+			"Handwerker":                    DisciplineHand,
+			"Sammler":                       DisciplineLand,
+		},
+		classJobs: map[string]string{
+			"Gladiator":     "Gladiator",
+			"Paladin":       "Paladin",
+			"Warrior":       "Krieger",
+			"Marauder":      "Marodeur",
+			"Dark Knight":   "Dunkelritter",
+			"Gunbreaker":    "Revolverklinge",
+			"Pugilist":      "Faustkämpfer",
+			"Monk":          "Mönch",
+			"Lancer":        "Pikenier",
+			"Dragoon":       "Dragoon",
+			"Rogue":         "Schurke",
+			"Ninja":         "Ninja",
+			"Samurai":       "Samurai",
+			"Conjurer":      "Druide",
+			"White Mage":    "Weißmagier",
+			"Scholar":       "Gelehrter",
+			"Astrologian":   "Astrologe",
+			"Archer":        "Waldläufer",
+			"Bard":          "Barde",
+			"Machinist":     "Maschinist",
+			"Dancer":        "Tänzer",
+			"Thaumaturge":   "Thaumaturg",
+			"Black Mage":    "Schwarzmagier",
+			"Arcanist":      "Hermetiker",
+			"Summoner":      "Beschwörer",
+			"Red Mage":      "Rotmagier",
+			"Blue Mage":     "Blaumagier",
+			"Carpenter":     "Zimmerer",
+			"Blacksmith":    "Grobschmied",
+			"Armorer":       "Plattner",
+			"Goldsmith":     "Goldschmied",
+			"Leatherworker": "Gerber",
+			"Weaver":        "Weber",
+			"Alchemist":     "Alchemist",
+			"Culinarian":    "Gourmet",
+			"Miner":         "Minenarbeiter",
+			"Botanist":      "Gärtner",
+			"Fisher":        "Fischer",
+		},
+		attributes: map[string]string{
+			"Stärke":               "Strength",
+			"Geschick":             "Dexterity",
+			"Konstitution":         "Vitality",
+			"Intelligenz":          "Intelligence",
+			"Willenskraft":         "Mind",
+			"Kritischer Treffer":   "Critical Hit Rate",
+			"Entschlossenheit":     "Determination",
+			"Direkter Treffer":     "Direct Hit Rate",
+			"Verteidigung":         "Defense",
+			"Magieabwehr":          "Magic Defense",
+			"Angriffskraft":        "Attack Power",
+			"Schnelligkeit":        "Skill Speed",
+			"Angriffsmagie":        "Attack Magic Potency",
+			"Heilmagie":            "Healing Magic Potency",
+			"Zaubertempo":          "Spell Speed",
+			"Unerschütterlichkeit": "Tenacity",
+			"Frömmigkeit":          "Piety",
+			"LP":                   "HP",
+			"HP":                   "CP",
+			"SP":                   "GP",
+		},
+		races: map[string]Race{
+			"Hyuran":   RaceHyur,
+			"Elezen":   RaceElezen,
+			"Lalafell": RaceLalafell,
+			"Miqo'te":  RaceMiqote,
+			"Roegadyn": RaceRoegadyn,
+			"Au Ra":    RaceAuRa,
+			"Hrothgar": RaceHrothgar,
+			"Viera":    RaceViera,
+		},
+		clans: map[string]Clan{
+			"Wiesländer":   ClanMidlander,
+			"Hochländer":   ClanHighlander,
+			"Erlschatten":  ClanWildwood,
+			"Dunkelalb":    ClanDuskwight,
+			"Halmling":     ClanPlainsfolk,
+			"Sandling":     ClanDunesfolk,
+			"Goldtatze":    ClanSeekerOfTheSun,
+			"Mondstreuner": ClanKeeperOfTheMoon,
+			"Seewolf":      ClanSeaWolf,
+			"Lohengarde":   ClanHellsguard,
+			"Raen":         ClanRaen,
+			"Xaela":        ClanXaela,
+			"Helion":       ClanHelions,
+			"Losgesandt":   ClanTheLost,
+			"Rava":         ClanRava,
+			"Veena":        ClanVeena,
+		},
+		namedayRegex: regexp.MustCompile(`^(?P<sun>\d+)\. Sonne im (?P<moon>\d+)\. (?P<phase>Astral|Umbral)mond$`),
+		umbral:       "Umbral",
+		fcHeadings: fcHeadings{
+			formed:        "Gründung",
+			activeMembers: "Aktive Mitglieder",
+			rank:          "Rang",
+			active:        "Aktivitätszeit",
+		},
+		recruitmentOpen: "Offen",
+	},
+	lodestone.LanguageFrench: {
+		achievementRegex: regexp.MustCompile(`haut fait « ?(.+?) ?»`),
+		privateRegex:     regexp.MustCompile(`(?i)privé|non public`),
+		disciplines: map[string]string{
+			"Tank":                    DisciplineWar,
+			"DPS de mêlée":            DisciplineWar,
+			"DPS physique à distance": DisciplineWar,
+			"Soigneur":                DisciplineMagic,
+			"DPS magique à distance":  DisciplineMagic,
+			"Disciples de la Main":    DisciplineHand,
+			"Disciples de la Terre":   DisciplineLand,
+		},
+		classJobs: map[string]string{
+			"Gladiator":     "Gladiateur",
+			"Paladin":       "Paladin",
+			"Warrior":       "Guerrier",
+			"Marauder":      "Maraudeur",
+			"Dark Knight":   "Chevalier noir",
+			"Gunbreaker":    "Pistosabreur",
+			"Pugilist":      "Pugiliste",
+			"Monk":          "Moine",
+			"Lancer":        "Maître d'hast",
+			"Dragoon":       "Chevalier dragon",
+			"Rogue":         "Surineur",
+			"Ninja":         "Ninja",
+			"Samurai":       "Samouraï",
+			"Conjurer":      "Élémentaliste",
+			"White Mage":    "Mage blanc",
+			"Scholar":       "Érudit",
+			"Astrologian":   "Astromancien",
+			"Archer":        "Archer",
+			"Bard":          "Barde",
+			"Machinist":     "Machiniste",
+			"Dancer":        "Danseur",
+			"Thaumaturge":   "Occultiste",
+			"Black Mage":    "Mage noir",
+			"Arcanist":      "Arcaniste",
+			"Summoner":      "Invocateur",
+			"Red Mage":      "Mage rouge",
+			"Blue Mage":     "Mage bleu",
+			"Carpenter":     "Menuisier",
+			"Blacksmith":    "Forgeron",
+			"Armorer":       "Armurier",
+			"Goldsmith":     "Orfèvre",
+			"Leatherworker": "Tanneur",
+			"Weaver":        "Couturier",
+			"Alchemist":     "Alchimiste",
+			"Culinarian":    "Cuisinier",
+			"Miner":         "Mineur",
+			"Botanist":      "Botaniste",
+			"Fisher":        "Pêcheur",
+		},
+		attributes: map[string]string{
+			"Force":                       "Strength",
+			"Dextérité":                   "Dexterity",
+			"Vitalité":                    "Vitality",
+			"Intelligence":                "Intelligence",
+			"Esprit":                      "Mind",
+			"Critique":                    "Critical Hit Rate",
+			"Détermination":               "Determination",
+			"Coup direct":                 "Direct Hit Rate",
+			"Défense":                     "Defense",
+			"Défense magique":             "Magic Defense",
+			"Puissance d'attaque":         "Attack Power",
+			"Vivacité":                    "Skill Speed",
+			"Puissance magique offensive": "Attack Magic Potency",
+			"Puissance magique curative":  "Healing Magic Potency",
+			"Célérité":                    "Spell Speed",
+			"Ténacité":                    "Tenacity",
+			"Piété":                       "Piety",
+			"PV":                          "HP",
+			"PM":                          "MP",
+			"PS":                          "CP",
+			"PR":                          "GP",
+		},
+		races: map[string]Race{
+			"Hyur":     RaceHyur,
+			"Élézen":   RaceElezen,
+			"Lalafell": RaceLalafell,
+			"Miqo'te":  RaceMiqote,
+			"Roegadyn": RaceRoegadyn,
+			"Ao Ra":    RaceAuRa,
+			"Hrothgar": RaceHrothgar,
+			"Viéra":    RaceViera,
+		},
+		clans: map[string]Clan{
+			"Hyurois des plaines":       ClanMidlander,
+			"Hyurois des hautes terres": ClanHighlander,
+			"Sylvestre":                 ClanWildwood,
+			"Crépusculaire":             ClanDuskwight,
+			"Peuple des Plaines":        ClanPlainsfolk,
+			"Peuple des Dunes":          ClanDunesfolk,
+			"Tribu du Soleil":           ClanSeekerOfTheSun,
+			"Tribu de la Lune":          ClanKeeperOfTheMoon,
+			"Clan de la Mer":            ClanSeaWolf,
+			"Clan du Feu":               ClanHellsguard,
+			"Raen":                      ClanRaen,
+			"Xaela":                     ClanXaela,
+			"Hellion":                   ClanHelions,
+			"Égaré":                     ClanTheLost,
+			"Rava":                      ClanRava,
+			"Veena":                     ClanVeena,
+		},
+		namedayRegex: regexp.MustCompile(
+			`(?i)^(?P<sun>\d+)(?:er|e) Soleil de la (?P<moon>\d+)(?:re|e) Lune (?P<phase>astrale|ombrale)$`,
+		),
+		umbral: "ombrale",
+		fcHeadings: fcHeadings{
+			formed:        "Date de création",
+			activeMembers: "Membres actifs",
+			rank:          "Rang",
+			active:        "Période d'activité",
+		},
+		recruitmentOpen: "Ouvert",
+	},
+}
+
+// language returns the language lodestone pages are requested in for the given context
+func (api *FFXIVAPI) language(ctx context.Context) string {
+	if lang := lodestone.LanguageFromContext(ctx); lodestone.ValidLanguage(lang) {
+		return lang
+	}
+	if lang := lodestone.RegionLanguage(lodestone.RegionFromContext(ctx)); lang != "" {
+		return lang
+	}
+	if lodestone.ValidLanguage(api.Language) {
+		return api.Language
+	}
+	return lodestone.LanguageEnglish
+}
+
+// locale returns the locale pages requested with the given context should be parsed with
+func (api *FFXIVAPI) locale(ctx context.Context) *locale {
+	return locales[api.language(ctx)]
+}
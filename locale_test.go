@@ -0,0 +1,86 @@
+package ffxivapi
+
+import (
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+)
+
+func TestParseNamedayLocales(t *testing.T) {
+	for _, tc := range []struct {
+		lang string
+		raw  string
+		date *EorzeanDate
+	}{
+		{lang: lodestone.LanguageEnglish, raw: "22nd Sun of the 5th Astral Moon", date: &EorzeanDate{Sun: 22, Moon: 9}},
+		{lang: lodestone.LanguageEnglish, raw: "1st Sun of the 6th Umbral Moon", date: &EorzeanDate{Sun: 1, Moon: 12}},
+		{lang: lodestone.LanguageJapanese, raw: "星5月 22日", date: &EorzeanDate{Sun: 22, Moon: 9}},
+		{lang: lodestone.LanguageJapanese, raw: "霊6月1日", date: &EorzeanDate{Sun: 1, Moon: 12}},
+		{lang: lodestone.LanguageGerman, raw: "22. Sonne im 5. Astralmond", date: &EorzeanDate{Sun: 22, Moon: 9}},
+		{lang: lodestone.LanguageGerman, raw: "1. Sonne im 6. Umbralmond", date: &EorzeanDate{Sun: 1, Moon: 12}},
+		{lang: lodestone.LanguageFrench, raw: "22e Soleil de la 5e Lune astrale", date: &EorzeanDate{Sun: 22, Moon: 9}},
+		{lang: lodestone.LanguageFrench, raw: "1er Soleil de la 6e lune ombrale", date: &EorzeanDate{Sun: 1, Moon: 12}},
+		// Dates are only parsed in the language of the page
+		{lang: lodestone.LanguageGerman, raw: "22nd Sun of the 5th Astral Moon"},
+		{lang: lodestone.LanguageJapanese, raw: "星7月 22日"},
+	} {
+		nameday := parseNameday(tc.raw, locales[tc.lang])
+		if nameday.Raw != tc.raw {
+			t.Errorf("%s %q: expected raw to be kept, got %q", tc.lang, tc.raw, nameday.Raw)
+		}
+
+		switch {
+		case tc.date == nil && nameday.Date != nil:
+			t.Errorf("%s %q: expected no date, got %+v", tc.lang, tc.raw, *nameday.Date)
+		case tc.date != nil && nameday.Date == nil:
+			t.Errorf("%s %q: expected %+v, got no date", tc.lang, tc.raw, *tc.date)
+		case tc.date != nil && *nameday.Date != *tc.date:
+			t.Errorf("%s %q: expected %+v, got %+v", tc.lang, tc.raw, *tc.date, *nameday.Date)
+		}
+	}
+}
+
+func TestLocaleNames(t *testing.T) {
+	for _, tc := range []struct {
+		lang     string
+		race     string
+		clan     string
+		guardian string
+
+		expectedRace     Race
+		expectedClan     Clan
+		expectedGuardian Guardian
+	}{
+		{
+			lang: lodestone.LanguageEnglish, race: "Miqo'te", clan: "Seeker of the Sun", guardian: "Nophica, the Matron",
+			expectedRace: RaceMiqote, expectedClan: ClanSeekerOfTheSun, expectedGuardian: GuardianNophica,
+		},
+		{
+			lang: lodestone.LanguageJapanese, race: "ミコッテ", clan: "サンシーカー", guardian: "ノフィカ",
+			expectedRace: RaceMiqote, expectedClan: ClanSeekerOfTheSun, expectedGuardian: GuardianNophica,
+		},
+		{
+			lang: lodestone.LanguageGerman, race: "Hyuran", clan: "Wiesländer", guardian: "Halone - Die Furie",
+			expectedRace: RaceHyur, expectedClan: ClanMidlander, expectedGuardian: GuardianHalone,
+		},
+		{
+			lang: lodestone.LanguageFrench, race: "Ao Ra", clan: "Xaela", guardian: "Nald'thal, les Marchands",
+			expectedRace: RaceAuRa, expectedClan: ClanXaela, expectedGuardian: GuardianNaldthal,
+		},
+		// Unknown names are reported as displayed
+		{
+			lang: lodestone.LanguageEnglish, race: "Garlean", clan: "Pureblood", guardian: "Zodiark",
+			expectedRace: "Garlean", expectedClan: "Pureblood", expectedGuardian: "Zodiark",
+		},
+	} {
+		loc := locales[tc.lang]
+		if race := loc.race(tc.race); race != tc.expectedRace {
+			t.Errorf("%s: expected race %q, got %q", tc.lang, tc.expectedRace, race)
+		}
+		if clan := loc.clan(tc.clan); clan != tc.expectedClan {
+			t.Errorf("%s: expected clan %q, got %q", tc.lang, tc.expectedClan, clan)
+		}
+		if guardian := loc.guardian(tc.guardian); guardian != tc.expectedGuardian {
+			t.Errorf("%s: expected guardian %q, got %q", tc.lang, tc.expectedGuardian, guardian)
+		}
+	}
+}
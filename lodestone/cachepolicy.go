@@ -0,0 +1,91 @@
+package lodestone
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CacheRule sets how long responses to the lodestone paths matching Pattern are cached
+type CacheRule struct {
+	Pattern *regexp.Regexp
+	// MaxAge applies to successful responses
+	MaxAge time.Duration
+	// NotFoundMaxAge applies to 404 responses, which are not cached if it is zero
+	NotFoundMaxAge time.Duration
+}
+
+// maxAge returns the max age of a response with the given status code
+func (cr CacheRule) maxAge(statusCode int) time.Duration {
+	if statusCode == http.StatusNotFound {
+		return cr.NotFoundMaxAge
+	}
+	return cr.MaxAge
+}
+
+// cacheable returns whether responses with the given status code are cached
+func (cr CacheRule) cacheable(statusCode int) bool {
+	return statusCode < 400 || (statusCode == http.StatusNotFound && cr.NotFoundMaxAge > 0)
+}
+
+// CachePolicy is a list of rules matched in order against the path of each lodestone request
+type CachePolicy []CacheRule
+
+// DefaultCachePolicy caches each kind of lodestone page according to how often it changes
+var DefaultCachePolicy = CachePolicy{
+	{Pattern: regexp.MustCompile(`^/lodestone/character/\d+/?$`), MaxAge: 30 * time.Minute, NotFoundMaxAge: 30 * time.Minute},
+	{Pattern: regexp.MustCompile(`^/lodestone/character/\d+/(achievement|mount|minion)/`), MaxAge: time.Hour, NotFoundMaxAge: 30 * time.Minute},
+	{Pattern: regexp.MustCompile(`^/lodestone/(character|freecompany|linkshell|crossworld_linkshell)/?$`), MaxAge: 5 * time.Minute},
+	{Pattern: regexp.MustCompile(`^/lodestone/news/`), MaxAge: 5 * time.Minute},
+	{Pattern: regexp.MustCompile(`.`), MaxAge: 15 * time.Minute, NotFoundMaxAge: 30 * time.Minute},
+}
+
+// Rule returns the first rule matching path, and whether any did
+func (cp CachePolicy) Rule(path string) (CacheRule, bool) {
+	for _, rule := range cp {
+		if rule.Pattern.MatchString(path) {
+			return rule, true
+		}
+	}
+
+	return CacheRule{}, false
+}
+
+// ParseCachePolicy parses a list of rules separated by newlines or semicolons. Each rule is made of a regular
+// expression, the max age of successful responses and, optionally, the one of 404 responses, separated by spaces, such
+// as `^/lodestone/character/\d+/$ 30m 30m`
+func ParseCachePolicy(s string) (CachePolicy, error) {
+	var policy CachePolicy
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ';' }) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("cache rule %q must have a pattern and one or two max ages", line)
+		}
+
+		pattern, err := regexp.Compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("cache rule %q: %w", line, err)
+		}
+
+		rule := CacheRule{Pattern: pattern}
+		rule.MaxAge, err = time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("cache rule %q: %w", line, err)
+		}
+		if len(fields) == 3 {
+			rule.NotFoundMaxAge, err = time.ParseDuration(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("cache rule %q: %w", line, err)
+			}
+		}
+
+		policy = append(policy, rule)
+	}
+
+	return policy, nil
+}
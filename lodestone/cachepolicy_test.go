@@ -0,0 +1,115 @@
+package lodestone
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaultCachePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		path           string
+		maxAge         time.Duration
+		notFoundMaxAge time.Duration
+	}{
+		{path: "/lodestone/character/31688528/", maxAge: 30 * time.Minute, notFoundMaxAge: 30 * time.Minute},
+		{path: "/lodestone/character/31688528/achievement/", maxAge: time.Hour, notFoundMaxAge: 30 * time.Minute},
+		{path: "/lodestone/character/31688528/mount/", maxAge: time.Hour, notFoundMaxAge: 30 * time.Minute},
+		{path: "/lodestone/character/", maxAge: 5 * time.Minute},
+		{path: "/lodestone/freecompany/", maxAge: 5 * time.Minute},
+		{path: "/lodestone/news/", maxAge: 5 * time.Minute},
+		{path: "/lodestone/freecompany/9237023573225362244/", maxAge: 15 * time.Minute, notFoundMaxAge: 30 * time.Minute},
+		{path: "/lodestone/character/31688528/class_job/", maxAge: 15 * time.Minute, notFoundMaxAge: 30 * time.Minute},
+	} {
+		rule, found := DefaultCachePolicy.Rule(tc.path)
+		if !found {
+			t.Errorf("%s: expected a rule to match", tc.path)
+			continue
+		}
+		if rule.MaxAge != tc.maxAge || rule.NotFoundMaxAge != tc.notFoundMaxAge {
+			t.Errorf("%s: expected %v and %v for 404, got %v and %v",
+				tc.path, tc.maxAge, tc.notFoundMaxAge, rule.MaxAge, rule.NotFoundMaxAge)
+		}
+	}
+}
+
+func TestCacheRuleStatus(t *testing.T) {
+	withNotFound := CacheRule{MaxAge: time.Hour, NotFoundMaxAge: time.Minute}
+	withoutNotFound := CacheRule{MaxAge: time.Hour}
+
+	for _, tc := range []struct {
+		name       string
+		rule       CacheRule
+		statusCode int
+		cacheable  bool
+		maxAge     time.Duration
+	}{
+		{name: "ok", rule: withNotFound, statusCode: http.StatusOK, cacheable: true, maxAge: time.Hour},
+		{name: "not found", rule: withNotFound, statusCode: http.StatusNotFound, cacheable: true, maxAge: time.Minute},
+		{name: "not found without max age", rule: withoutNotFound, statusCode: http.StatusNotFound},
+		{name: "forbidden", rule: withNotFound, statusCode: http.StatusForbidden, maxAge: time.Hour},
+		{name: "unavailable", rule: withNotFound, statusCode: http.StatusServiceUnavailable, maxAge: time.Hour},
+	} {
+		if cacheable := tc.rule.cacheable(tc.statusCode); cacheable != tc.cacheable {
+			t.Errorf("%s: expected cacheable to be %v, got %v", tc.name, tc.cacheable, cacheable)
+		}
+		if maxAge := tc.rule.maxAge(tc.statusCode); maxAge != tc.maxAge {
+			t.Errorf("%s: expected max age %v, got %v", tc.name, tc.maxAge, maxAge)
+		}
+	}
+}
+
+func TestParseCachePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policy   string
+		path     string
+		expected *CacheRule
+		err      bool
+	}{
+		{
+			name: "single rule", policy: `^/lodestone/character/\d+/$ 1h`, path: "/lodestone/character/1/",
+			expected: &CacheRule{MaxAge: time.Hour},
+		},
+		{
+			name: "not found max age", policy: `^/lodestone/character/\d+/$ 1h 10m`, path: "/lodestone/character/1/",
+			expected: &CacheRule{MaxAge: time.Hour, NotFoundMaxAge: 10 * time.Minute},
+		},
+		{
+			name: "first match wins", policy: "^/lodestone/news/ 1m; ^/lodestone/ 2m", path: "/lodestone/news/",
+			expected: &CacheRule{MaxAge: time.Minute},
+		},
+		{
+			name: "newlines and blank rules", policy: "^/lodestone/news/ 1m\n\n  \n^/lodestone/ 2m;", path: "/lodestone/linkshell/1/",
+			expected: &CacheRule{MaxAge: 2 * time.Minute},
+		},
+		{name: "no match", policy: "^/lodestone/news/ 1m", path: "/lodestone/character/1/"},
+		{name: "missing max age", policy: "^/lodestone/news/", err: true},
+		{name: "too many fields", policy: "^/lodestone/news/ 1m 1m 1m", err: true},
+		{name: "invalid pattern", policy: "^/lodestone/(news 1m", err: true},
+		{name: "invalid max age", policy: "^/lodestone/news/ soon", err: true},
+		{name: "invalid not found max age", policy: "^/lodestone/news/ 1m later", err: true},
+	} {
+		policy, err := ParseCachePolicy(tc.policy)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		rule, found := policy.Rule(tc.path)
+		switch {
+		case tc.expected == nil && found:
+			t.Errorf("%s: expected no rule to match, got %+v", tc.name, rule)
+		case tc.expected != nil && !found:
+			t.Errorf("%s: expected a rule to match", tc.name)
+		case tc.expected != nil && (rule.MaxAge != tc.expected.MaxAge || rule.NotFoundMaxAge != tc.expected.NotFoundMaxAge):
+			t.Errorf("%s: expected %+v, got %+v", tc.name, *tc.expected, rule)
+		}
+	}
+}
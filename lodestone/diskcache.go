@@ -0,0 +1,272 @@
+package lodestone
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"roob.re/tcache"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores the serialized responses of TCacheRoundTripper. It is implemented by *tcache.Cache, *DiskCache and
+// *RedisCache
+type Cache interface {
+	// Access calls handler.Then with the entry stored under key if it is younger than maxAge. Otherwise, it calls
+	// handler.Else to write a new one, which is only stored if it returns no error. A zero maxAge never accepts an entry
+	// stored before the access started, which TCacheRoundTripper relies on to replace entries, although handler.Then may
+	// still be called with one written by a concurrent access in the meantime
+	Access(key string, maxAge time.Duration, handler tcache.Handler) error
+}
+
+// ContextCache is a Cache whose accesses can stop waiting for concurrent ones writing the same entry. It is implemented
+// by *DiskCache and *RedisCache
+type ContextCache interface {
+	Cache
+	// AccessContext behaves like Access, but returns ctx.Err() without calling handler if ctx is done while waiting for
+	// another access to write the entry
+	AccessContext(ctx context.Context, key string, maxAge time.Duration, handler tcache.Handler) error
+}
+
+// accessContext accesses the entry stored under key in cache, giving up waiting for it once ctx is done if cache is a
+// ContextCache
+func accessContext(ctx context.Context, cache Cache, key string, maxAge time.Duration, handler tcache.Handler) error {
+	if cc, ok := cache.(ContextCache); ok {
+		return cc.AccessContext(ctx, key, maxAge, handler)
+	}
+
+	return cache.Access(key, maxAge, handler)
+}
+
+// tempPrefix is prepended to the name of entries which are still being written
+const tempPrefix = ".tmp-"
+
+// DiskCache stores entries as files in a directory, which survive restarts. Once their total size exceeds the size
+// cap, the least recently used ones are removed. Fresh entries are read without any locking, while concurrent misses
+// for the same key wait for the first one to be written instead of querying the lodestone again. Several processes may
+// share the same directory, although each of them only enforces the size cap on the entries it has written or found
+// when started, and only coalesces its own misses
+type DiskCache struct {
+	dir     string
+	maxSize int64
+
+	mtx  sync.Mutex
+	size int64
+	// lru holds *diskEntry, most recently used first
+	lru     *list.List
+	entries map[string]*list.Element
+	// writing holds the entries being written, whose channel is closed once they are
+	writing map[string]chan struct{}
+}
+
+// diskEntry is a file known to a DiskCache
+type diskEntry struct {
+	name string
+	size int64
+}
+
+// NewDiskCache returns a DiskCache storing up to maxSize bytes in dir, which is created if it does not exist. Entries
+// already in dir are kept, and considered to have been used in the order they were written
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading cache directory: %w", err)
+	}
+
+	dc := &DiskCache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element, len(files)),
+		writing: make(map[string]chan struct{}),
+	}
+
+	infos := make([]os.FileInfo, 0, len(files))
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		// Leftovers of writes interrupted by a previous shutdown
+		if strings.HasPrefix(info.Name(), tempPrefix) {
+			_ = os.Remove(filepath.Join(dir, info.Name()))
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		dc.entries[info.Name()] = dc.lru.PushBack(&diskEntry{name: info.Name(), size: info.Size()})
+		dc.size += info.Size()
+	}
+	dc.evict("")
+
+	log.Infof("Disk cache in %s holds %d entries, %d bytes", dir, dc.lru.Len(), dc.size)
+	return dc, nil
+}
+
+func (dc *DiskCache) Access(key string, maxAge time.Duration, handler tcache.Handler) error {
+	return dc.AccessContext(context.Background(), key, maxAge, handler)
+}
+
+func (dc *DiskCache) AccessContext(ctx context.Context, key string, maxAge time.Duration, handler tcache.Handler) error {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	start := time.Now()
+
+	if read, err := dc.read(name, func(modTime time.Time) bool {
+		return maxAge > 0 && time.Since(modTime) <= maxAge
+	}, handler.Then); read {
+		return err
+	}
+
+	var done chan struct{}
+	for {
+		var writing bool
+		dc.mtx.Lock()
+		done, writing = dc.writing[name]
+		if !writing {
+			done = make(chan struct{})
+			dc.writing[name] = done
+			dc.mtx.Unlock()
+			break
+		}
+		dc.mtx.Unlock()
+
+		// Another access is writing the entry, which is good enough even if maxAge is zero as long as it was written
+		// after this one started. If writing it failed, this access tries to write it itself
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if read, err := dc.read(name, func(modTime time.Time) bool {
+			return (maxAge > 0 && time.Since(modTime) <= maxAge) || !modTime.Before(start)
+		}, handler.Then); read {
+			return err
+		}
+	}
+
+	defer func() {
+		dc.mtx.Lock()
+		delete(dc.writing, name)
+		dc.mtx.Unlock()
+		close(done)
+	}()
+
+	return dc.write(name, handler.Else)
+}
+
+// read calls then with the entry stored under name if it exists and fresh accepts its modification time, returning
+// whether it did so. Freshness is checked on the file itself, so entries written by other processes are also found
+func (dc *DiskCache) read(name string, fresh func(modTime time.Time) bool, then func(io.Reader) error) (bool, error) {
+	file, err := os.Open(filepath.Join(dc.dir, name))
+	if err != nil {
+		return false, nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || !fresh(info.ModTime()) {
+		return false, nil
+	}
+
+	dc.touch(name, info.Size())
+	return true, then(file)
+}
+
+// write stores under name the entry written by elseFn, keeping the previous one if it fails. The entry is written to a
+// temporary file first, so readers never see it half written
+func (dc *DiskCache) write(name string, elseFn func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(dc.dir, tempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("creating cache entry: %w", err)
+	}
+
+	err = elseFn(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	info, err := os.Stat(tmp.Name())
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dc.dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		log.Warnf("could not store cache entry: %v", err)
+		return nil
+	}
+
+	dc.touch(name, info.Size())
+	dc.evict(name)
+	return nil
+}
+
+// touch marks an entry as the most recently used, adding it to the index if it was not known
+func (dc *DiskCache) touch(name string, size int64) {
+	dc.mtx.Lock()
+	defer dc.mtx.Unlock()
+
+	if elem, found := dc.entries[name]; found {
+		entry := elem.Value.(*diskEntry)
+		dc.size += size - entry.size
+		entry.size = size
+		dc.lru.MoveToFront(elem)
+		return
+	}
+
+	dc.entries[name] = dc.lru.PushFront(&diskEntry{name: name, size: size})
+	dc.size += size
+}
+
+// evict removes the least recently used entries until the cache fits its size cap, sparing the one named keep
+func (dc *DiskCache) evict(keep string) {
+	dc.mtx.Lock()
+	defer dc.mtx.Unlock()
+
+	for elem := dc.lru.Back(); elem != nil && dc.size > dc.maxSize; {
+		entry := elem.Value.(*diskEntry)
+		prev := elem.Prev()
+		if entry.name != keep {
+			// Readers already holding the file open can still read it after it is removed
+			if err := os.Remove(filepath.Join(dc.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+				log.Warnf("could not evict cache entry: %v", err)
+			}
+
+			dc.lru.Remove(elem)
+			delete(dc.entries, entry.name)
+			dc.size -= entry.size
+		}
+		elem = prev
+	}
+}
+
+// Size returns the total size in bytes of the entries known to the cache
+func (dc *DiskCache) Size() int64 {
+	dc.mtx.Lock()
+	defer dc.mtx.Unlock()
+	return dc.size
+}
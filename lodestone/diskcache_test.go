@@ -0,0 +1,319 @@
+package lodestone
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"roob.re/tcache"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// accessString reads an entry from c, writing value if it is missing, and returns the entry and whether it was a hit
+func accessString(t *testing.T, c Cache, key string, maxAge time.Duration, value string) (string, bool) {
+	t.Helper()
+
+	var read string
+	hit := false
+	err := c.Access(key, maxAge, tcache.Handler{
+		Then: func(r io.Reader) error {
+			data, err := ioutil.ReadAll(r)
+			read, hit = string(data), true
+			return err
+		},
+		Else: func(w io.Writer) error {
+			read = value
+			_, err := io.WriteString(w, value)
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatalf("accessing %q: %v", key, err)
+	}
+
+	return read, hit
+}
+
+func TestCacheZeroMaxAge(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		cache Cache
+	}{
+		{name: "disk", cache: dc},
+		{name: "redis", cache: NewRedisCache(newFakeRedis(t).addr(), 2)},
+		{name: "memory", cache: newMemCache()},
+	} {
+		accessString(t, tc.cache, "key", time.Hour, "first")
+
+		// Even an entry stored right before is replaced
+		if read, hit := accessString(t, tc.cache, "key", 0, "second"); read != "second" || hit {
+			t.Errorf("%s: expected zero max age to write a new entry, got %q (hit: %v)", tc.name, read, hit)
+		}
+		if read, hit := accessString(t, tc.cache, "key", time.Hour, "third"); read != "second" || !hit {
+			t.Errorf("%s: expected the new entry to be stored, got %q (hit: %v)", tc.name, read, hit)
+		}
+	}
+}
+
+func TestDiskCacheAccess(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		key      string
+		maxAge   time.Duration
+		value    string
+		expected string
+		hit      bool
+	}{
+		{name: "miss", key: "a", maxAge: time.Hour, value: "first", expected: "first"},
+		{name: "hit", key: "a", maxAge: time.Hour, value: "second", expected: "first", hit: true},
+		{name: "other key", key: "b", maxAge: time.Hour, value: "other", expected: "other"},
+		{name: "expired", key: "a", maxAge: 0, value: "third", expected: "third"},
+		{name: "hit after refresh", key: "a", maxAge: time.Hour, value: "fourth", expected: "third", hit: true},
+	} {
+		read, hit := accessString(t, dc, tc.key, tc.maxAge, tc.value)
+		if read != tc.expected || hit != tc.hit {
+			t.Errorf("%s: expected %q (hit: %v), got %q (hit: %v)", tc.name, tc.expected, tc.hit, read, hit)
+		}
+	}
+}
+
+func TestDiskCacheFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	dc, err := NewDiskCache(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessString(t, dc, "key", time.Hour, "stored")
+
+	errLodestone := errors.New("lodestone is down")
+	err = dc.Access("key", 0, tcache.Handler{
+		Then: func(r io.Reader) error {
+			t.Error("expired entry should not be read")
+			return nil
+		},
+		Else: func(w io.Writer) error {
+			_, _ = io.WriteString(w, "half written")
+			return errLodestone
+		},
+	})
+	if err != errLodestone {
+		t.Errorf("expected %v, got %v", errLodestone, err)
+	}
+
+	if read, hit := accessString(t, dc, "key", time.Hour, "new"); read != "stored" || !hit {
+		t.Errorf("expected previous entry to be kept, got %q (hit: %v)", read, hit)
+	}
+
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), tempPrefix) {
+			t.Errorf("temporary file %s was not removed", file.Name())
+		}
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	value := strings.Repeat("x", 100)
+
+	// Room for three entries
+	dc, err := NewDiskCache(dir, 350)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		// access is the key accessed in this step
+		access string
+		// cached are the keys expected to be cached after it
+		cached  []string
+		evicted []string
+	}{
+		{name: "first", access: "a", cached: []string{"a"}},
+		{name: "fill", access: "b", cached: []string{"a", "b"}},
+		{name: "full", access: "c", cached: []string{"a", "b", "c"}},
+		{name: "use a", access: "a", cached: []string{"a", "b", "c"}},
+		{name: "evict b", access: "d", cached: []string{"a", "c", "d"}, evicted: []string{"b"}},
+		{name: "evict c", access: "e", cached: []string{"a", "d", "e"}, evicted: []string{"b", "c"}},
+	} {
+		accessString(t, dc, tc.access, time.Hour, value)
+
+		if size := dc.Size(); size != int64(len(tc.cached)*len(value)) {
+			t.Errorf("%s: expected size %d, got %d", tc.name, len(tc.cached)*len(value), size)
+		}
+
+		// Entries are looked for on disk, as accessing missing ones would write them again
+		for _, key := range tc.evicted {
+			if _, err := os.Stat(filepath.Join(dir, diskName(key))); !os.IsNotExist(err) {
+				t.Errorf("%s: expected %q to be evicted", tc.name, key)
+			}
+		}
+		for _, key := range tc.cached {
+			if _, err := os.Stat(filepath.Join(dir, diskName(key))); err != nil {
+				t.Errorf("%s: expected %q to be cached: %v", tc.name, key, err)
+			}
+		}
+	}
+
+	// Entries are found again after a restart
+	reloaded, err := NewDiskCache(dir, 350)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := reloaded.Size(); size != 3*int64(len(value)) {
+		t.Errorf("expected reloaded cache to hold %d bytes, got %d", 3*len(value), size)
+	}
+	if _, hit := accessString(t, reloaded, "e", time.Hour, value); !hit {
+		t.Error("expected reloaded cache to hold e")
+	}
+}
+
+func TestDiskCacheConcurrentMisses(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessString(t, dc, "stale", time.Hour, "stale")
+
+	release := make(chan struct{})
+	writing := make(chan struct{})
+	writes := 0
+	mtx := sync.Mutex{}
+
+	// A slow refresh of one entry, such as a background refresh of a stale response
+	refreshed := make(chan struct{})
+	go func() {
+		_ = dc.Access("stale", 0, tcache.Handler{
+			Then: func(r io.Reader) error { return nil },
+			Else: func(w io.Writer) error {
+				close(writing)
+				<-release
+				_, err := io.WriteString(w, "refreshed")
+				return err
+			},
+		})
+		close(refreshed)
+	}()
+	<-writing
+
+	// Neither the stale entry nor other keys wait for it
+	if read, hit := accessString(t, dc, "stale", time.Hour, "unexpected"); read != "stale" || !hit {
+		t.Errorf("expected stale entry to be served while refreshing, got %q (hit: %v)", read, hit)
+	}
+	if read, hit := accessString(t, dc, "other", time.Hour, "other"); read != "other" || hit {
+		t.Errorf("expected other key to be written while refreshing, got %q (hit: %v)", read, hit)
+	}
+
+	// Concurrent misses of the entry being written wait for it instead of writing it again
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var read []byte
+			_ = dc.Access("stale", 0, tcache.Handler{
+				Then: func(r io.Reader) error {
+					var err error
+					read, err = ioutil.ReadAll(r)
+					return err
+				},
+				Else: func(w io.Writer) error {
+					mtx.Lock()
+					writes++
+					mtx.Unlock()
+					read = []byte("rewritten")
+					_, err := io.WriteString(w, "rewritten")
+					return err
+				},
+			})
+			if string(read) != "refreshed" {
+				t.Errorf("expected waiter to read the refreshed entry, got %q", read)
+			}
+		}()
+	}
+
+	// Give the waiters time to reach the entry being written
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	<-refreshed
+
+	if writes != 0 {
+		t.Errorf("expected waiters not to write the entry, got %d writes", writes)
+	}
+}
+
+func TestDiskCacheWaiterCancelled(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writing := make(chan struct{})
+	release := make(chan struct{})
+	written := make(chan error)
+	go func() {
+		written <- dc.Access("key", time.Hour, tcache.Handler{
+			Then: func(r io.Reader) error { return nil },
+			Else: func(w io.Writer) error {
+				close(writing)
+				<-release
+				_, err := io.WriteString(w, "written")
+				return err
+			},
+		})
+	}()
+	<-writing
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err = dc.AccessContext(ctx, "key", time.Hour, tcache.Handler{
+		Then: func(r io.Reader) error {
+			t.Error("cancelled waiter should not read the entry")
+			return nil
+		},
+		Else: func(w io.Writer) error {
+			t.Error("cancelled waiter should not write the entry")
+			return nil
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected waiter to give up once cancelled, took %v", elapsed)
+	}
+
+	close(release)
+	if err := <-written; err != nil {
+		t.Errorf("unexpected writer error: %v", err)
+	}
+}
+
+// diskName returns the name of the file an entry is stored in
+func diskName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
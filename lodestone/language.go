@@ -0,0 +1,78 @@
+package lodestone
+
+import (
+	"context"
+	"regexp"
+)
+
+// Languages the lodestone is available in
+const (
+	LanguageEnglish  = "en"
+	LanguageJapanese = "ja"
+	LanguageGerman   = "de"
+	LanguageFrench   = "fr"
+)
+
+// acceptLanguages holds the accept-language header sent for each language
+var acceptLanguages = map[string]string{
+	LanguageEnglish:  "en-US,en;q=0.5",
+	LanguageJapanese: "ja-JP,ja;q=0.5",
+	LanguageGerman:   "de-DE,de;q=0.5",
+	LanguageFrench:   "fr-FR,fr;q=0.5",
+}
+
+// languageRegions holds the lodestone region serving pages in each language. English is served by both eu and na
+var languageRegions = map[string]string{
+	LanguageEnglish:  RegionNA,
+	LanguageJapanese: RegionJP,
+	LanguageGerman:   RegionDE,
+	LanguageFrench:   RegionFR,
+}
+
+// ValidLanguage returns whether lang is one of the languages the lodestone is available in
+func ValidLanguage(lang string) bool {
+	_, found := acceptLanguages[lang]
+	return found
+}
+
+type languageKey struct{}
+
+// WithLanguage returns a copy of ctx which makes clients request lodestone pages in the given language
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// LanguageFromContext returns the language set in ctx by WithLanguage, or an empty string if none was set
+func LanguageFromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
+
+// canonServerRegex matches the canonical lodestone servers, capturing their region
+var canonServerRegex = regexp.MustCompile(`^https://(eu|na|jp|de|fr)\.finalfantasyxiv\.com/?$`)
+
+// serverForLanguage returns the server that should be queried to obtain pages in the given language
+// As the lodestone chooses the language based on the region, canonical servers are swapped by one serving the
+// language if they do not, while custom servers are returned unmodified
+func serverForLanguage(server string, lang string) string {
+	matches := canonServerRegex.FindStringSubmatch(server)
+	if len(matches) < 2 {
+		return server
+	}
+
+	if regionLanguages[matches[1]] == lang {
+		return server
+	}
+
+	return CanonServerFromRegion(languageRegions[lang])
+}
+
+// serverLanguage returns the language served by a canonical lodestone server, or English for custom servers
+func serverLanguage(server string) string {
+	matches := canonServerRegex.FindStringSubmatch(server)
+	if len(matches) < 2 {
+		return LanguageEnglish
+	}
+
+	return regionLanguages[matches[1]]
+}
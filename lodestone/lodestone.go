@@ -1,10 +1,10 @@
 package lodestone
 
 import (
+	"context"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
-	"math/rand"
 	"net/http"
 	"strings"
 	"time"
@@ -16,6 +16,26 @@ type Client interface {
 	Request(query string) (io.ReadCloser, error)
 }
 
+// ContextClient is a Client whose requests can be aborted, and routed or localized with the values of their context
+type ContextClient interface {
+	Client
+	// RequestContext behaves like Request, but aborts the request and any pending retries when ctx is done
+	RequestContext(ctx context.Context, query string) (io.ReadCloser, error)
+}
+
+// RequestContext makes a request with client, passing ctx along if it is a ContextClient. Requests made with other
+// clients cannot be aborted once started, and ignore the region and language set in ctx
+func RequestContext(ctx context.Context, client Client, query string) (io.ReadCloser, error) {
+	if cc, ok := client.(ContextClient); ok {
+		return cc.RequestContext(ctx, query)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return client.Request(query)
+}
+
 // HTTPError is a non-200 status code from the lodestone server implemented as error
 type HTTPError int
 
@@ -23,6 +43,7 @@ func (lhe HTTPError) Error() string {
 	return fmt.Sprintf("lodestone returned status %d %s", lhe, http.StatusText(int(lhe)))
 }
 
+// LodestoneHTTPTimeout is the time budget of the default retry policy
 const LodestoneHTTPTimeout = 20 * time.Second
 
 func CanonServerFromRegion(region string) string {
@@ -33,67 +54,87 @@ func CanonServerFromRegion(region string) string {
 type HTTPClient struct {
 	Server     string
 	HTTPClient *http.Client
+	// Language is used for requests whose context does not specify one with WithLanguage. Defaults to the language
+	// served by Server
+	Language string
+	// RetryPolicy decides which failed requests are retried. Defaults to a LinearRetryPolicy with a budget of
+	// LodestoneHTTPTimeout
+	RetryPolicy RetryPolicy
 }
 
 func (hlp *HTTPClient) Request(query string) (io.ReadCloser, error) {
-	u := strings.TrimSuffix(hlp.Server, "/") + "/" + strings.TrimPrefix(query, "/")
+	return hlp.RequestContext(context.Background(), query)
+}
+
+func (hlp *HTTPClient) RequestContext(ctx context.Context, query string) (io.ReadCloser, error) {
+	lang := LanguageFromContext(ctx)
+	if lang == "" {
+		lang = hlp.Language
+	}
+	if !ValidLanguage(lang) {
+		lang = serverLanguage(hlp.Server)
+	}
 
-	request, err := http.NewRequest(http.MethodGet, u, nil)
+	u := strings.TrimSuffix(serverForLanguage(hlp.Server, lang), "/") + "/" + strings.TrimPrefix(query, "/")
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	request.Header.Add("accept-language", "en-US,en;q=0.5")
+	request.Header.Add("accept-language", acceptLanguages[lang])
 	request.Header.Add("user-agent", "Mozilla/5.0 (Windows NT 10.0; WOW64; rv:77.0) Gecko/20100101 Firefox/81.0")
 	request.Header.Add("DNT", "1")
 
+	policy := hlp.RetryPolicy
+	if policy == nil {
+		policy = defaultRetryPolicy
+	}
+
 	var response *http.Response
 	try := 1
 	start := time.Now()
 	for {
 		response, err = hlp.HTTPClient.Do(request)
-		if err != nil { // Request failed hard, return error
-			return nil, err
-		}
-
 		// Everything went ok, break retry loop
-		if response.StatusCode == http.StatusOK {
+		if err == nil && response.StatusCode == http.StatusOK {
 			break
 		}
 
-		// Return error if status code is not retry-able
-		if !shouldRetry(response.StatusCode) || time.Since(start) > LodestoneHTTPTimeout {
+		// Body of non-200 responses is not used
+		if err == nil {
+			_ = response.Body.Close()
+		}
+
+		// Errors caused by the caller giving up are not worth retrying
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		wait, retry := policy.Retry(try, time.Since(start), response, err)
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
 			return nil, HTTPError(response.StatusCode)
 		}
 
-		// Linear backoff, wait between n and n+3 seconds where n is the attempt number
-		wait := time.Second * time.Duration(retryMultiplier(response.StatusCode)*float64(1+rand.Intn(try+2)))
-		log.Warnf("Lodestone replied with %d, retrying in %fs", response.StatusCode, wait.Seconds())
-		time.Sleep(wait)
+		if err != nil {
+			log.Warnf("Lodestone request failed with %v, retrying in %fs", err, wait.Seconds())
+		} else {
+			log.Warnf("Lodestone replied with %d, retrying in %fs", response.StatusCode, wait.Seconds())
+		}
+
+		// Abort waiting if the caller is no longer interested in the response
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
 		try++
 	}
 
 	return response.Body, nil
 }
-
-// shouldRetry returns whether the non-200 status code is considered transient, and therefore the request should be retried
-func shouldRetry(statusCode int) bool {
-	switch statusCode {
-	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
-		return true
-	default:
-		return false
-	}
-}
-
-// retryMultiplier returns a factor for the naive linear backoff algorithm
-func retryMultiplier(statusCode int) float64 {
-	switch statusCode {
-	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
-		return 2
-	case http.StatusBadGateway:
-		return 3
-	default:
-		return 1
-	}
-}
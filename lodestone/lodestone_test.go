@@ -0,0 +1,123 @@
+package lodestone
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientCancel(t *testing.T) {
+	var requests int32
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if rq.URL.Path == "/slow/" {
+			select {
+			case <-rq.Context().Done():
+			case <-unblock:
+			}
+			return
+		}
+
+		// The default retry policy waits for at least a second before retrying
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	client := &HTTPClient{Server: server.URL, HTTPClient: server.Client()}
+
+	for _, tc := range []struct {
+		name     string
+		query    string
+		cancel   time.Duration
+		requests int32
+	}{
+		{name: "waiting to retry", query: "/unavailable/", cancel: 50 * time.Millisecond, requests: 1},
+		{name: "waiting for the response", query: "/slow/", cancel: 50 * time.Millisecond, requests: 1},
+		{name: "cancelled before starting", query: "/unavailable/", requests: 0},
+	} {
+		atomic.StoreInt32(&requests, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if tc.cancel > 0 {
+			time.AfterFunc(tc.cancel, cancel)
+		} else {
+			cancel()
+		}
+
+		start := time.Now()
+		body, err := client.RequestContext(ctx, tc.query)
+		elapsed := time.Since(start)
+		cancel()
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected %v, got %v", tc.name, context.Canceled, err)
+		}
+		if body != nil {
+			_ = body.Close()
+			t.Errorf("%s: expected no body", tc.name)
+		}
+		if elapsed > 500*time.Millisecond {
+			t.Errorf("%s: expected to return once cancelled, took %v", tc.name, elapsed)
+		}
+		if n := atomic.LoadInt32(&requests); n != tc.requests {
+			t.Errorf("%s: expected %d requests, got %d", tc.name, tc.requests, n)
+		}
+	}
+}
+
+// plainClient only implements Client, counting its requests
+type plainClient struct {
+	requests int
+}
+
+func (pc *plainClient) Request(query string) (io.ReadCloser, error) {
+	pc.requests++
+	return ioutil.NopCloser(strings.NewReader("plain")), nil
+}
+
+func TestRequestContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		name     string
+		ctx      context.Context
+		client   Client
+		expected string
+		err      error
+	}{
+		{name: "context client", ctx: context.Background(), client: namedClient("named"), expected: "named"},
+		{name: "plain client", ctx: context.Background(), client: &plainClient{}, expected: "plain"},
+		{name: "plain client cancelled", ctx: cancelled, client: &plainClient{}, err: context.Canceled},
+	} {
+		body, err := RequestContext(tc.ctx, tc.client, "/lodestone/")
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+			}
+			if pc, ok := tc.client.(*plainClient); ok && pc.requests != 0 {
+				t.Errorf("%s: expected no request to be made, got %d", tc.name, pc.requests)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		data, _ := ioutil.ReadAll(body)
+		_ = body.Close()
+		if string(data) != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, data)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package lodestone
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default limits applied by NewDefaultRateLimiter
+const (
+	DefaultRate        = 10
+	DefaultBurst       = 20
+	DefaultMaxInFlight = 8
+)
+
+// RateLimiter throttles requests using a token bucket, which allows bursts of up to burst requests and then refills at
+// rate requests per second, and caps the number of concurrent requests with a semaphore
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// inFlight is nil if concurrency is not limited
+	inFlight chan struct{}
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate requests per second, in bursts of up to burst requests, with at
+// most maxInFlight of them running concurrently. A rate or maxInFlight of zero disables the respective limit
+func NewRateLimiter(rate float64, burst int, maxInFlight int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	rl := &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+	if maxInFlight > 0 {
+		rl.inFlight = make(chan struct{}, maxInFlight)
+	}
+
+	return rl
+}
+
+// NewDefaultRateLimiter returns a RateLimiter with the default limits, which are conservative enough not to be
+// throttled by the lodestone
+func NewDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(DefaultRate, DefaultBurst, DefaultMaxInFlight)
+}
+
+// Acquire blocks until a request can be made or ctx is done. On success, the returned function must be called once
+// the request is finished to free its concurrency slot
+func (rl *RateLimiter) Acquire(ctx context.Context) (release func(), err error) {
+	if rl.inFlight != nil {
+		select {
+		case rl.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release = func() {}
+	if rl.inFlight != nil {
+		var once sync.Once
+		release = func() {
+			once.Do(func() { <-rl.inFlight })
+		}
+	}
+
+	if err := rl.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// wait takes a token from the bucket, waiting for it to be refilled if it is empty
+func (rl *RateLimiter) wait(ctx context.Context) error {
+	if rl.rate <= 0 {
+		return nil
+	}
+
+	// The token is taken right away, possibly leaving the bucket in debt, so concurrent callers queue up in order
+	rl.mtx.Lock()
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+	rl.tokens--
+	debt := -rl.tokens
+	rl.mtx.Unlock()
+
+	if debt <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(debt / rl.rate * float64(time.Second)))
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		// Give the token back, as the request will not be made
+		rl.mtx.Lock()
+		rl.tokens++
+		rl.mtx.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimitedRoundTripper throttles requests made through RoundTripper with Limiter. Requests count as in flight
+// until their response body is closed
+type RateLimitedRoundTripper struct {
+	RoundTripper http.RoundTripper
+	Limiter      *RateLimiter
+}
+
+func (rrt *RateLimitedRoundTripper) RoundTrip(rq *http.Request) (*http.Response, error) {
+	release, err := rrt.Limiter.Acquire(rq.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := rrt.RoundTripper.RoundTrip(rq)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release}
+	return response, nil
+}
+
+// releasingBody frees the concurrency slot of a request when its body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (rb *releasingBody) Close() error {
+	defer rb.release()
+	return rb.ReadCloser.Close()
+}
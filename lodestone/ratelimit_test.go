@@ -0,0 +1,169 @@
+package lodestone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripperFunc implements http.RoundTripper with a function
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(rq *http.Request) (*http.Response, error) {
+	return f(rq)
+}
+
+// tokensLeft returns the tokens left in the bucket of rl, which are negative if it is in debt
+func (rl *RateLimiter) tokensLeft() float64 {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	return rl.tokens
+}
+
+func TestRateLimiterDebt(t *testing.T) {
+	// A rate low enough for refills during the test to be negligible
+	rl := NewRateLimiter(1, 3, 0)
+
+	for _, tc := range []struct {
+		name   string
+		tokens float64
+		wait   bool
+	}{
+		{name: "burst 1", tokens: 2},
+		{name: "burst 2", tokens: 1},
+		{name: "burst 3", tokens: 0},
+		{name: "debt", tokens: -1, wait: true},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := rl.Acquire(ctx)
+		cancel()
+
+		if tc.wait {
+			// The request gives up waiting, and its token is given back
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%s: expected to wait until the deadline, got %v", tc.name, err)
+			}
+			if tokens := rl.tokensLeft(); math.Abs(tokens) > 0.1 {
+				t.Errorf("%s: expected the token to be given back, %f left", tc.name, tokens)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if tokens := rl.tokensLeft(); math.Abs(tokens-tc.tokens) > 0.1 {
+			t.Errorf("%s: expected %f tokens left, got %f", tc.name, tc.tokens, tokens)
+		}
+	}
+}
+
+func TestRateLimiterRate(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rate     float64
+		burst    int
+		requests int
+		minTime  time.Duration
+		maxTime  time.Duration
+	}{
+		{name: "within burst", rate: 10, burst: 5, requests: 5, maxTime: 50 * time.Millisecond},
+		// Requests beyond the burst are spaced 1/rate apart
+		{name: "beyond burst", rate: 50, burst: 2, requests: 7, minTime: 90 * time.Millisecond, maxTime: 200 * time.Millisecond},
+		{name: "unlimited", rate: 0, burst: 1, requests: 100, maxTime: 50 * time.Millisecond},
+	} {
+		rl := NewRateLimiter(tc.rate, tc.burst, 0)
+
+		start := time.Now()
+		for i := 0; i < tc.requests; i++ {
+			release, err := rl.Acquire(context.Background())
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", tc.name, err)
+			}
+			release()
+		}
+
+		if elapsed := time.Since(start); elapsed < tc.minTime || elapsed > tc.maxTime {
+			t.Errorf("%s: expected %d requests to take between %v and %v, took %v",
+				tc.name, tc.requests, tc.minTime, tc.maxTime, elapsed)
+		}
+	}
+}
+
+func TestRateLimiterInFlight(t *testing.T) {
+	rl := NewRateLimiter(0, 1, 2)
+
+	first, err := rl.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := rl.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := rl.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected third request to wait for a free slot, got %v", err)
+	}
+
+	// Releasing twice frees a single slot
+	first()
+	first()
+	if _, err := rl.Acquire(context.Background()); err != nil {
+		t.Errorf("expected a slot to be free after releasing, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := rl.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected double release not to free two slots, got %v", err)
+	}
+
+	second()
+}
+
+func TestRateLimitedRoundTripper(t *testing.T) {
+	rl := NewRateLimiter(0, 1, 1)
+	rrt := &RateLimitedRoundTripper{
+		RoundTripper: roundTripperFunc(func(rq *http.Request) (*http.Response, error) {
+			if rq.URL.Path == "/error" {
+				return nil, errors.New("connection reset")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("body"))}, nil
+		}),
+		Limiter: rl,
+	}
+
+	// Failed requests free their slot right away
+	rq, _ := http.NewRequest(http.MethodGet, "https://eu.finalfantasyxiv.com/error", nil)
+	if _, err := rrt.RoundTrip(rq); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	rq, _ = http.NewRequest(http.MethodGet, "https://eu.finalfantasyxiv.com/lodestone/", nil)
+	response, err := rrt.RoundTrip(rq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Successful requests keep their slot until the body is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := rrt.RoundTrip(rq.WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected request to wait for the body to be closed, got %v", err)
+	}
+
+	_ = response.Body.Close()
+	if response, err := rrt.RoundTrip(rq); err != nil {
+		t.Errorf("expected slot to be freed once the body is closed, got %v", err)
+	} else {
+		_ = response.Body.Close()
+	}
+}
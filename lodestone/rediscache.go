@@ -0,0 +1,205 @@
+package lodestone
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"roob.re/tcache"
+	"strconv"
+	"time"
+)
+
+// unlockScript deletes a lock only if it is still held with the given token, so a lock which expired and was taken by
+// another replica is not released by mistake
+const unlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// RedisCache stores entries in a server speaking the redis protocol, so they can be shared by several replicas.
+// Replicas missing the same entry take a lock on it, so only one of them queries the lodestone while the rest wait
+// for the result. If the server cannot be reached, entries are obtained without being cached
+type RedisCache struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix is prepended to every key
+	Prefix string
+	// TTL is the time after which redis removes an entry, which should exceed the longest max age it is accessed with
+	TTL time.Duration
+	// LockTimeout is the time after which a lock expires, in case the replica holding it dies. Replicas waiting for it
+	// give up and query the lodestone themselves after the same time
+	LockTimeout time.Duration
+	// Timeout applies to connecting to the server and to each command
+	Timeout time.Duration
+
+	pool chan *redisConn
+}
+
+// NewRedisCache returns a RedisCache for the server at addr, keeping up to poolSize idle connections to it
+func NewRedisCache(addr string, poolSize int) *RedisCache {
+	return &RedisCache{
+		Addr:        addr,
+		Prefix:      "ffxivapi:",
+		TTL:         7 * 24 * time.Hour,
+		LockTimeout: 30 * time.Second,
+		Timeout:     2 * time.Second,
+		pool:        make(chan *redisConn, poolSize),
+	}
+}
+
+// lockPoll is the interval at which replicas waiting for a lock check whether the entry has been written
+const lockPoll = 100 * time.Millisecond
+
+func (rc *RedisCache) Access(key string, maxAge time.Duration, handler tcache.Handler) error {
+	return rc.AccessContext(context.Background(), key, maxAge, handler)
+}
+
+func (rc *RedisCache) AccessContext(ctx context.Context, key string, maxAge time.Duration, handler tcache.Handler) error {
+	entryKey := rc.Prefix + "entry:" + key
+	lockKey := rc.Prefix + "lock:" + key
+	start := time.Now()
+
+	storedAt, data, err := rc.get(entryKey)
+	if err != nil {
+		log.Warnf("redis cache unavailable, not caching: %v", err)
+		return handler.Else(&bytes.Buffer{})
+	}
+	if data != nil && maxAge > 0 && time.Since(storedAt) <= maxAge {
+		return handler.Then(bytes.NewReader(data))
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	deadline := start.Add(rc.LockTimeout)
+	for {
+		locked, err := rc.lock(lockKey, token)
+		if err != nil {
+			log.Warnf("redis cache unavailable, not caching: %v", err)
+			return handler.Else(&bytes.Buffer{})
+		}
+		if locked {
+			break
+		}
+
+		// Another replica is writing the entry, which is good enough even if maxAge is zero as it was written after
+		// this access started
+		poll := time.NewTimer(lockPoll)
+		select {
+		case <-poll.C:
+		case <-ctx.Done():
+			poll.Stop()
+			return ctx.Err()
+		}
+
+		storedAt, data, err = rc.get(entryKey)
+		if err == nil && data != nil && !storedAt.Before(start) {
+			return handler.Then(bytes.NewReader(data))
+		}
+
+		if time.Now().After(deadline) {
+			log.Warnf("timed out waiting for redis cache lock on %s, not caching", key)
+			return handler.Else(&bytes.Buffer{})
+		}
+	}
+	defer rc.unlock(lockKey, token)
+
+	buf := &bytes.Buffer{}
+	if err := handler.Else(buf); err != nil {
+		return err
+	}
+
+	if err := rc.set(entryKey, buf.Bytes()); err != nil {
+		log.Warnf("could not store redis cache entry: %v", err)
+	}
+	return nil
+}
+
+// get returns an entry and the time it was stored at, or a nil slice if it does not exist
+func (rc *RedisCache) get(key string) (time.Time, []byte, error) {
+	reply, err := rc.do("GET", key)
+	if err == errRedisNil {
+		return time.Time{}, nil, nil
+	}
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	// Entries are stored as the unix time in milliseconds followed by a newline and the data
+	value, ok := reply.([]byte)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+	newline := bytes.IndexByte(value, '\n')
+	if newline < 0 {
+		return time.Time{}, nil, nil
+	}
+	millis, err := strconv.ParseInt(string(value[:newline]), 10, 64)
+	if err != nil {
+		return time.Time{}, nil, nil
+	}
+
+	return time.UnixMilli(millis), value[newline+1:], nil
+}
+
+// set stores an entry with the current time
+func (rc *RedisCache) set(key string, data []byte) error {
+	value := strconv.FormatInt(time.Now().UnixMilli(), 10) + "\n" + string(data)
+	_, err := rc.do("SET", key, value, "PX", strconv.FormatInt(rc.TTL.Milliseconds(), 10))
+	return err
+}
+
+// lock tries to take the lock with the given token, returning whether it was free
+func (rc *RedisCache) lock(key, token string) (bool, error) {
+	_, err := rc.do("SET", key, token, "NX", "PX", strconv.FormatInt(rc.LockTimeout.Milliseconds(), 10))
+	if err == errRedisNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlock releases the lock if it is still held with the given token
+func (rc *RedisCache) unlock(key, token string) {
+	if _, err := rc.do("EVAL", unlockScript, "1", key, token); err != nil {
+		log.Warnf("could not release redis cache lock: %v", err)
+	}
+}
+
+// do runs a command on a pooled connection
+func (rc *RedisCache) do(args ...string) (interface{}, error) {
+	var conn *redisConn
+	select {
+	case conn = <-rc.pool:
+	default:
+		var err error
+		conn, err = dialRedis(rc.Addr, rc.Password, rc.DB, rc.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := conn.do(rc.Timeout, args...)
+	if conn.broken {
+		_ = conn.Close()
+		return reply, err
+	}
+
+	select {
+	case rc.pool <- conn:
+	default:
+		_ = conn.Close()
+	}
+	return reply, err
+}
+
+// randomToken returns a random string identifying the holder of a lock
+func randomToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("could not generate lock token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
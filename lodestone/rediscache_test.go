@@ -0,0 +1,365 @@
+package lodestone
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"roob.re/tcache"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process server speaking enough of the redis protocol for RedisCache
+type fakeRedis struct {
+	listener net.Listener
+
+	mtx     sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+// newFakeRedis starts a fakeRedis listening on a random local port, which is stopped when the test ends
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fr := &fakeRedis{
+		listener: listener,
+		values:   map[string]string{},
+		expires:  map[string]time.Time{},
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fr.serve(conn)
+		}
+	}()
+
+	return fr
+}
+
+func (fr *fakeRedis) addr() string {
+	return fr.listener.Addr().String()
+}
+
+// serve answers the commands sent through conn until it is closed
+func (fr *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+
+	for {
+		var n int
+		if _, err := fmt.Fscanf(rd, "*%d\r\n", &n); err != nil {
+			return
+		}
+
+		args := make([]string, n)
+		for i := range args {
+			var size int
+			if _, err := fmt.Fscanf(rd, "$%d\r\n", &size); err != nil {
+				return
+			}
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(rd, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+
+		if _, err := io.WriteString(conn, fr.command(args)); err != nil {
+			return
+		}
+	}
+}
+
+// command runs a command and returns its encoded reply
+func (fr *fakeRedis) command(args []string) string {
+	fr.mtx.Lock()
+	defer fr.mtx.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, found := fr.get(args[1])
+		if !found {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		key, value := args[1], args[2]
+		var ttl time.Duration
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				i++
+				millis, _ := strconv.Atoi(args[i])
+				ttl = time.Duration(millis) * time.Millisecond
+			}
+		}
+
+		if _, found := fr.get(key); found && nx {
+			return "$-1\r\n"
+		}
+		fr.set(key, value, ttl)
+		return "+OK\r\n"
+	case "EVAL":
+		// The only script run is the one releasing locks
+		if args[1] != unlockScript {
+			return "-ERR unknown script\r\n"
+		}
+		if value, found := fr.get(args[3]); found && value == args[4] {
+			delete(fr.values, args[3])
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// get returns the value of a key which has not expired. fr.mtx must be held
+func (fr *fakeRedis) get(key string) (string, bool) {
+	if expires, found := fr.expires[key]; found && time.Now().After(expires) {
+		delete(fr.values, key)
+		delete(fr.expires, key)
+	}
+
+	value, found := fr.values[key]
+	return value, found
+}
+
+// set stores a key, which expires after ttl unless it is zero. fr.mtx must be held
+func (fr *fakeRedis) set(key, value string, ttl time.Duration) {
+	fr.values[key] = value
+	delete(fr.expires, key)
+	if ttl > 0 {
+		fr.expires[key] = time.Now().Add(ttl)
+	}
+}
+
+// has returns whether key is stored
+func (fr *fakeRedis) has(key string) bool {
+	fr.mtx.Lock()
+	defer fr.mtx.Unlock()
+
+	_, found := fr.get(key)
+	return found
+}
+
+func TestRedisCacheAccess(t *testing.T) {
+	fr := newFakeRedis(t)
+	rc := NewRedisCache(fr.addr(), 2)
+
+	for _, tc := range []struct {
+		name     string
+		key      string
+		maxAge   time.Duration
+		value    string
+		expected string
+		hit      bool
+	}{
+		{name: "miss", key: "a", maxAge: time.Hour, value: "first", expected: "first"},
+		{name: "hit", key: "a", maxAge: time.Hour, value: "second", expected: "first", hit: true},
+		{name: "other key", key: "b", maxAge: time.Hour, value: "other", expected: "other"},
+		{name: "expired", key: "a", maxAge: 0, value: "third", expected: "third"},
+		{name: "hit after refresh", key: "a", maxAge: time.Hour, value: "fourth", expected: "third", hit: true},
+	} {
+		read, hit := accessString(t, rc, tc.key, tc.maxAge, tc.value)
+		if read != tc.expected || hit != tc.hit {
+			t.Errorf("%s: expected %q (hit: %v), got %q (hit: %v)", tc.name, tc.expected, tc.hit, read, hit)
+		}
+	}
+
+	if fr.has(rc.Prefix + "lock:a") {
+		t.Error("expected lock to be released")
+	}
+}
+
+func TestRedisCacheLockContention(t *testing.T) {
+	fr := newFakeRedis(t)
+
+	// Each replica has its own client
+	writer, waiter := NewRedisCache(fr.addr(), 2), NewRedisCache(fr.addr(), 2)
+
+	writing := make(chan struct{})
+	release := make(chan struct{})
+	written := make(chan error)
+	go func() {
+		written <- writer.Access("key", time.Hour, tcache.Handler{
+			Then: func(r io.Reader) error {
+				t.Error("writer should not find the entry")
+				return nil
+			},
+			Else: func(w io.Writer) error {
+				close(writing)
+				<-release
+				_, err := io.WriteString(w, "written")
+				return err
+			},
+		})
+	}()
+	<-writing
+
+	// The waiter finds the lock taken, and reads the entry once the writer stores it
+	go func() {
+		time.Sleep(2 * lockPoll)
+		close(release)
+	}()
+
+	var read []byte
+	err := waiter.Access("key", time.Hour, tcache.Handler{
+		Then: func(r io.Reader) error {
+			var err error
+			read, err = ioutil.ReadAll(r)
+			return err
+		},
+		Else: func(w io.Writer) error {
+			t.Error("waiter should not write the entry")
+			return nil
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected waiter error: %v", err)
+	}
+	if string(read) != "written" {
+		t.Errorf("expected waiter to read the written entry, got %q", read)
+	}
+
+	if err := <-written; err != nil {
+		t.Errorf("unexpected writer error: %v", err)
+	}
+}
+
+func TestRedisCacheLockTimeout(t *testing.T) {
+	fr := newFakeRedis(t)
+	rc := NewRedisCache(fr.addr(), 2)
+	rc.LockTimeout = 3 * lockPoll
+
+	// A lock held by a replica which died before writing the entry
+	fr.mtx.Lock()
+	fr.set(rc.Prefix+"lock:key", "dead replica", time.Hour)
+	fr.mtx.Unlock()
+
+	start := time.Now()
+	read, hit := accessString(t, rc, "key", time.Hour, "fetched")
+	if read != "fetched" || hit {
+		t.Errorf("expected entry to be fetched after the lock timed out, got %q (hit: %v)", read, hit)
+	}
+	if elapsed := time.Since(start); elapsed < rc.LockTimeout {
+		t.Errorf("expected to wait for the lock for %v, gave up after %v", rc.LockTimeout, elapsed)
+	}
+
+	// The lock is still held by someone else, so the entry is not stored
+	if fr.has(rc.Prefix + "entry:key") {
+		t.Error("expected entry not to be stored without the lock")
+	}
+}
+
+func TestRedisCacheWaiterCancelled(t *testing.T) {
+	fr := newFakeRedis(t)
+	rc := NewRedisCache(fr.addr(), 2)
+
+	// A lock held by a replica taking long to write the entry
+	fr.mtx.Lock()
+	fr.set(rc.Prefix+"lock:key", "slow replica", time.Hour)
+	fr.mtx.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := rc.AccessContext(ctx, "key", time.Hour, tcache.Handler{
+		Then: func(r io.Reader) error {
+			t.Error("cancelled waiter should not read the entry")
+			return nil
+		},
+		Else: func(w io.Writer) error {
+			t.Error("cancelled waiter should not write the entry")
+			return nil
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected waiter to give up once cancelled, took %v", elapsed)
+	}
+}
+
+func TestRedisCacheUnavailable(t *testing.T) {
+	// Take a free port and release it, so nothing is listening on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	rc := NewRedisCache(addr, 2)
+	rc.Timeout = 100 * time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		read, hit := accessString(t, rc, "key", time.Hour, "fetched")
+		if read != "fetched" || hit {
+			t.Errorf("access %d: expected entry to be fetched without the cache, got %q (hit: %v)", i, read, hit)
+		}
+	}
+}
+
+func TestRedisRead(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		raw      string
+		expected interface{}
+		err      error
+	}{
+		{name: "status", raw: "+OK\r\n", expected: "OK"},
+		{name: "error", raw: "-ERR wrong type\r\n", err: RedisError("ERR wrong type")},
+		{name: "integer", raw: ":42\r\n", expected: int64(42)},
+		{name: "bulk", raw: "$5\r\nhello\r\n", expected: []byte("hello")},
+		{name: "bulk with newline", raw: "$7\r\n1\nhello\r\n", expected: []byte("1\nhello")},
+		{name: "empty bulk", raw: "$0\r\n\r\n", expected: []byte{}},
+		{name: "nil bulk", raw: "$-1\r\n", err: errRedisNil},
+		{name: "nil array", raw: "*-1\r\n", err: errRedisNil},
+		{
+			name: "array", raw: "*3\r\n:1\r\n$-1\r\n$2\r\nok\r\n",
+			expected: []interface{}{int64(1), nil, []byte("ok")},
+		},
+	} {
+		rc := &redisConn{rd: bufio.NewReader(strings.NewReader(tc.raw))}
+		reply, err := rc.read()
+		if err != tc.err {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(reply, tc.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tc.name, tc.expected, reply)
+		}
+	}
+
+	for _, raw := range []string{"", "OK\r\n", "+OK\n", "$5\r\nhel"} {
+		rc := &redisConn{rd: bufio.NewReader(strings.NewReader(raw))}
+		if _, err := rc.read(); err == nil {
+			t.Errorf("%q: expected an error", raw)
+		}
+	}
+}
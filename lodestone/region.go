@@ -0,0 +1,104 @@
+package lodestone
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Lodestone regions, each of them served from its own subdomain. Functions taking a region accept it in any case, so
+// the game regions reported for worlds ("EU", "NA" and "JP") can be used as well
+const (
+	RegionEU = "eu"
+	RegionNA = "na"
+	RegionJP = "jp"
+	RegionFR = "fr"
+	RegionDE = "de"
+)
+
+// Regions lists every lodestone region
+var Regions = []string{RegionEU, RegionNA, RegionJP, RegionFR, RegionDE}
+
+// regionLanguages holds the language each region serves its pages in
+var regionLanguages = map[string]string{
+	RegionEU: LanguageEnglish,
+	RegionNA: LanguageEnglish,
+	RegionJP: LanguageJapanese,
+	RegionFR: LanguageFrench,
+	RegionDE: LanguageGerman,
+}
+
+// ValidRegion returns whether region is one of the lodestone regions
+func ValidRegion(region string) bool {
+	_, found := regionLanguages[normalizeRegion(region)]
+	return found
+}
+
+// RegionLanguage returns the language the given region serves its pages in, or an empty string if it is unknown
+func RegionLanguage(region string) string {
+	return regionLanguages[normalizeRegion(region)]
+}
+
+// normalizeRegion returns the lowercase form regions are identified by
+func normalizeRegion(region string) string {
+	return strings.ToLower(strings.TrimSpace(region))
+}
+
+type regionKey struct{}
+
+// WithRegion returns a copy of ctx which makes a MultiRegionClient route requests to the given region
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, normalizeRegion(region))
+}
+
+// RegionFromContext returns the region set in ctx by WithRegion in lowercase, or an empty string if none was set
+func RegionFromContext(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return region
+}
+
+// MultiRegionClient routes each request to the client of the region set in its context with WithRegion, or to the
+// one of the default region if none was set
+type MultiRegionClient struct {
+	Clients map[string]Client
+	Default string
+}
+
+// NewMultiRegionClient returns a MultiRegionClient holding an HTTPClient for each of the canonical lodestone servers,
+// all of them using the given http.Client and RetryPolicy. A nil retryPolicy makes them use the default one
+func NewMultiRegionClient(httpClient *http.Client, defaultRegion string, retryPolicy RetryPolicy) *MultiRegionClient {
+	mrc := &MultiRegionClient{
+		Clients: make(map[string]Client, len(Regions)),
+		Default: defaultRegion,
+	}
+
+	for _, region := range Regions {
+		mrc.Clients[region] = &HTTPClient{
+			Server:      CanonServerFromRegion(region),
+			HTTPClient:  httpClient,
+			RetryPolicy: retryPolicy,
+		}
+	}
+
+	return mrc
+}
+
+func (mrc *MultiRegionClient) Request(query string) (io.ReadCloser, error) {
+	return mrc.RequestContext(context.Background(), query)
+}
+
+func (mrc *MultiRegionClient) RequestContext(ctx context.Context, query string) (io.ReadCloser, error) {
+	region := RegionFromContext(ctx)
+	if region == "" {
+		region = normalizeRegion(mrc.Default)
+	}
+
+	client, found := mrc.Clients[region]
+	if !found {
+		return nil, fmt.Errorf("no lodestone client for region %q", region)
+	}
+
+	return RequestContext(ctx, client, query)
+}
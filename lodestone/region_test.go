@@ -0,0 +1,82 @@
+package lodestone
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// namedClient answers every request with its own name
+type namedClient string
+
+func (nc namedClient) Request(query string) (io.ReadCloser, error) {
+	return nc.RequestContext(context.Background(), query)
+}
+
+func (nc namedClient) RequestContext(ctx context.Context, query string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(string(nc))), nil
+}
+
+func TestRegionCase(t *testing.T) {
+	for _, tc := range []struct {
+		region   string
+		valid    bool
+		language string
+	}{
+		{region: "eu", valid: true, language: LanguageEnglish},
+		{region: "EU", valid: true, language: LanguageEnglish},
+		{region: " Jp ", valid: true, language: LanguageJapanese},
+		{region: "DE", valid: true, language: LanguageGerman},
+		{region: "OCE"},
+		{region: ""},
+	} {
+		if valid := ValidRegion(tc.region); valid != tc.valid {
+			t.Errorf("%q: expected valid to be %v, got %v", tc.region, tc.valid, valid)
+		}
+		if language := RegionLanguage(tc.region); language != tc.language {
+			t.Errorf("%q: expected language %q, got %q", tc.region, tc.language, language)
+		}
+	}
+}
+
+func TestMultiRegionClient(t *testing.T) {
+	mrc := &MultiRegionClient{
+		Clients: map[string]Client{RegionEU: namedClient("eu"), RegionJP: namedClient("jp")},
+		Default: "EU",
+	}
+
+	for _, tc := range []struct {
+		region   string
+		expected string
+		err      bool
+	}{
+		{region: "", expected: "eu"},
+		{region: "jp", expected: "jp"},
+		{region: "JP", expected: "jp"},
+		{region: "na", err: true},
+	} {
+		ctx := context.Background()
+		if tc.region != "" {
+			ctx = WithRegion(ctx, tc.region)
+		}
+
+		body, err := mrc.RequestContext(ctx, "/lodestone/")
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.region)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.region, err)
+			continue
+		}
+
+		served, _ := ioutil.ReadAll(body)
+		if string(served) != tc.expected {
+			t.Errorf("%q: expected request to be served by %q, got %q", tc.region, tc.expected, served)
+		}
+	}
+}
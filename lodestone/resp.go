@@ -0,0 +1,136 @@
+package lodestone
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// errRedisNil is returned for nil replies, such as GET on a missing key
+var errRedisNil = errors.New("redis: nil reply")
+
+// RedisError is an error reply sent by a redis server
+type RedisError string
+
+func (re RedisError) Error() string {
+	return "redis: " + string(re)
+}
+
+// redisConn is a connection to a server speaking the redis protocol (RESP)
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	wr   *bufio.Writer
+	// broken is set after network or protocol errors, after which the connection must not be reused
+	broken bool
+}
+
+// dialRedis connects to a redis server, authenticating and selecting the given database if needed
+func dialRedis(addr, password string, db int, timeout time.Duration) (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &redisConn{conn: conn, rd: bufio.NewReader(conn), wr: bufio.NewWriter(conn)}
+	if password != "" {
+		if _, err := rc.do(timeout, "AUTH", password); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if db != 0 {
+		if _, err := rc.do(timeout, "SELECT", strconv.Itoa(db)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return rc, nil
+}
+
+// do sends a command and returns its reply, which is a string, []byte, int64, []interface{} or nil. Error replies
+// are returned as RedisError, and nil replies as errRedisNil
+func (rc *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	_ = rc.conn.SetDeadline(time.Now().Add(timeout))
+
+	fmt.Fprintf(rc.wr, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rc.wr, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := rc.wr.Flush(); err != nil {
+		rc.broken = true
+		return nil, err
+	}
+
+	reply, err := rc.read()
+	if err != nil {
+		var rerr RedisError
+		if !errors.As(err, &rerr) && err != errRedisNil {
+			rc.broken = true
+		}
+	}
+	return reply, err
+}
+
+// read parses a single reply
+func (rc *redisConn) read() (interface{}, error) {
+	line, err := rc.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rc.rd, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = rc.read()
+			if err != nil && err != errRedisNil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
+
+func (rc *redisConn) Close() error {
+	return rc.conn.Close()
+}
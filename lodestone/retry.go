@@ -0,0 +1,150 @@
+package lodestone
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides whether and when a failed lodestone request is retried
+type RetryPolicy interface {
+	// Retry is called after the attempt-th attempt of a request failed, elapsed after the first one started, either
+	// with a non-200 response or with a network error. It returns whether the request should be retried, and how
+	// long to wait before doing so. The body of response is already closed, but its headers are available
+	Retry(attempt int, elapsed time.Duration, response *http.Response, err error) (wait time.Duration, retry bool)
+}
+
+// defaultRetryPolicy is used by HTTPClients which do not specify a RetryPolicy
+var defaultRetryPolicy RetryPolicy = &LinearRetryPolicy{Budget: LodestoneHTTPTimeout}
+
+// LinearRetryPolicy retries transient status codes with a naive linear backoff, waiting between n and n+3 seconds
+// before the nth retry, scaled depending on the status code. If the lodestone asks for a longer wait with the
+// Retry-After header, that wait is used instead, as long as it fits in the budget. Network errors are not retried
+type LinearRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Zero means no limit
+	MaxAttempts int
+	// Budget is the time after which requests are no longer retried
+	Budget time.Duration
+}
+
+func (lrp *LinearRetryPolicy) Retry(attempt int, elapsed time.Duration, response *http.Response, err error) (time.Duration, bool) {
+	if err != nil || !shouldRetry(response.StatusCode) {
+		return 0, false
+	}
+
+	if (lrp.MaxAttempts > 0 && attempt >= lrp.MaxAttempts) || elapsed > lrp.Budget {
+		return 0, false
+	}
+
+	wait := time.Second * time.Duration(retryMultiplier(response.StatusCode)*float64(1+rand.Intn(attempt+2)))
+	if retryAfter, ok := RetryAfter(response); ok && retryAfter > wait {
+		if elapsed+retryAfter > lrp.Budget {
+			return 0, false
+		}
+		wait = retryAfter
+	}
+
+	return wait, true
+}
+
+// ExponentialRetryPolicy retries transient status codes and network errors with exponential backoff and full jitter,
+// honoring the Retry-After header if the lodestone sends it
+type ExponentialRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Zero means no limit
+	MaxAttempts int
+	// Budget is the total time a request may take. Retries which would start after it is exhausted are not made
+	Budget time.Duration
+	// BaseDelay is the maximum wait before the first retry, which doubles on each subsequent one up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// NewExponentialRetryPolicy returns an ExponentialRetryPolicy with sensible defaults for the lodestone
+func NewExponentialRetryPolicy() *ExponentialRetryPolicy {
+	return &ExponentialRetryPolicy{
+		MaxAttempts: 5,
+		Budget:      LodestoneHTTPTimeout,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    8 * time.Second,
+	}
+}
+
+func (erp *ExponentialRetryPolicy) Retry(attempt int, elapsed time.Duration, response *http.Response, err error) (time.Duration, bool) {
+	if err == nil && !shouldRetry(response.StatusCode) {
+		return 0, false
+	}
+
+	if erp.MaxAttempts > 0 && attempt >= erp.MaxAttempts {
+		return 0, false
+	}
+
+	backoff := erp.BaseDelay << (attempt - 1)
+	if backoff > erp.MaxDelay || backoff <= 0 {
+		backoff = erp.MaxDelay
+	}
+
+	var wait time.Duration
+	if backoff > 0 {
+		wait = time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+
+	// The lodestone knows better than us when it will be ready to serve requests again
+	if response != nil {
+		if retryAfter, ok := RetryAfter(response); ok {
+			wait = retryAfter
+		}
+	}
+
+	if erp.Budget > 0 && elapsed+wait > erp.Budget {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+// RetryAfter returns the wait requested by the Retry-After header of response, which may be either a number of
+// seconds or an HTTP date, and whether it was present and valid
+func RetryAfter(response *http.Response) (time.Duration, bool) {
+	header := response.Header.Get("retry-after")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// shouldRetry returns whether the non-200 status code is considered transient, and therefore the request should be retried
+func shouldRetry(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryMultiplier returns a factor for the naive linear backoff algorithm
+func retryMultiplier(statusCode int) float64 {
+	switch statusCode {
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return 2
+	case http.StatusBadGateway:
+		return 3
+	default:
+		return 1
+	}
+}
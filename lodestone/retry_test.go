@@ -0,0 +1,131 @@
+package lodestone
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// statusResponse returns a response with the given status code and headers, given as name and value pairs
+func statusResponse(statusCode int, headers ...string) *http.Response {
+	response := &http.Response{StatusCode: statusCode, Header: http.Header{}}
+	for i := 0; i+1 < len(headers); i += 2 {
+		response.Header.Set(headers[i], headers[i+1])
+	}
+	return response
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "missing"},
+		{name: "seconds", header: "120", expected: 2 * time.Minute, ok: true},
+		{name: "zero", header: "0", ok: true},
+		{name: "negative", header: "-5"},
+		{name: "garbage", header: "soon"},
+		{name: "past date", header: "Wed, 21 Oct 2015 07:28:00 GMT", ok: true},
+		{name: "future date", header: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), expected: time.Hour, ok: true},
+	} {
+		wait, ok := RetryAfter(statusResponse(http.StatusServiceUnavailable, "retry-after", tc.header))
+		if ok != tc.ok {
+			t.Errorf("%s: expected ok to be %v, got %v", tc.name, tc.ok, ok)
+		}
+
+		// Dates have a precision of one second, and some time passes since the header is built
+		if wait > tc.expected || wait < tc.expected-2*time.Second {
+			t.Errorf("%s: expected a wait of %v, got %v", tc.name, tc.expected, wait)
+		}
+	}
+}
+
+func TestExponentialRetryPolicy(t *testing.T) {
+	policy := &ExponentialRetryPolicy{
+		MaxAttempts: 4,
+		Budget:      10 * time.Second,
+		BaseDelay:   time.Second,
+		MaxDelay:    3 * time.Second,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		attempt  int
+		elapsed  time.Duration
+		response *http.Response
+		err      error
+
+		retry   bool
+		maxWait time.Duration
+		minWait time.Duration
+	}{
+		{name: "not found", attempt: 1, response: statusResponse(http.StatusNotFound)},
+		{name: "first retry", attempt: 1, response: statusResponse(http.StatusServiceUnavailable), retry: true, maxWait: time.Second},
+		{name: "second retry", attempt: 2, response: statusResponse(http.StatusBadGateway), retry: true, maxWait: 2 * time.Second},
+		{name: "capped", attempt: 3, response: statusResponse(http.StatusTooManyRequests), retry: true, maxWait: 3 * time.Second},
+		{name: "network error", attempt: 1, err: errors.New("connection reset"), retry: true, maxWait: time.Second},
+		{name: "max attempts", attempt: 4, response: statusResponse(http.StatusServiceUnavailable)},
+		{name: "budget exhausted", attempt: 1, elapsed: 11 * time.Second, response: statusResponse(http.StatusServiceUnavailable)},
+		{
+			name: "retry after", attempt: 1, response: statusResponse(http.StatusTooManyRequests, "retry-after", "5"),
+			retry: true, minWait: 5 * time.Second, maxWait: 5 * time.Second,
+		},
+		{
+			name: "retry after over budget", attempt: 1, elapsed: 6 * time.Second,
+			response: statusResponse(http.StatusTooManyRequests, "retry-after", "5"),
+		},
+	} {
+		wait, retry := policy.Retry(tc.attempt, tc.elapsed, tc.response, tc.err)
+		if retry != tc.retry {
+			t.Errorf("%s: expected retry to be %v, got %v", tc.name, tc.retry, retry)
+			continue
+		}
+		if wait < tc.minWait || wait > tc.maxWait {
+			t.Errorf("%s: expected a wait between %v and %v, got %v", tc.name, tc.minWait, tc.maxWait, wait)
+		}
+	}
+}
+
+func TestLinearRetryPolicy(t *testing.T) {
+	policy := &LinearRetryPolicy{Budget: 20 * time.Second}
+
+	for _, tc := range []struct {
+		name     string
+		elapsed  time.Duration
+		response *http.Response
+		err      error
+
+		retry   bool
+		minWait time.Duration
+		maxWait time.Duration
+	}{
+		{name: "not found", response: statusResponse(http.StatusNotFound)},
+		{name: "network error", err: errors.New("connection reset")},
+		{name: "unavailable", response: statusResponse(http.StatusServiceUnavailable), retry: true, minWait: 2 * time.Second, maxWait: 6 * time.Second},
+		{name: "budget exhausted", elapsed: 21 * time.Second, response: statusResponse(http.StatusServiceUnavailable)},
+		{
+			name: "longer retry after", response: statusResponse(http.StatusTooManyRequests, "retry-after", "10"),
+			retry: true, minWait: 10 * time.Second, maxWait: 10 * time.Second,
+		},
+		{
+			name: "shorter retry after", response: statusResponse(http.StatusBadGateway, "retry-after", "1"),
+			retry: true, minWait: 3 * time.Second, maxWait: 9 * time.Second,
+		},
+		{
+			name: "retry after over budget", elapsed: 15 * time.Second,
+			response: statusResponse(http.StatusTooManyRequests, "retry-after", "10"),
+		},
+	} {
+		wait, retry := policy.Retry(1, tc.elapsed, tc.response, tc.err)
+		if retry != tc.retry {
+			t.Errorf("%s: expected retry to be %v, got %v", tc.name, tc.retry, retry)
+			continue
+		}
+		if wait < tc.minWait || wait > tc.maxWait {
+			t.Errorf("%s: expected a wait between %v and %v, got %v", tc.name, tc.minWait, tc.maxWait, wait)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package lodestone
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Group coalesces concurrent calls sharing the same key, so only the first one runs and the rest wait for its result.
+// The zero value is ready to use
+type Group struct {
+	mtx   sync.Mutex
+	calls map[string]*call
+	stats GroupStats
+}
+
+// GroupStats counts the calls handled by a Group
+type GroupStats struct {
+	// Calls is the number of times a function was actually run
+	Calls uint64
+	// Coalesced is the number of callers which received the result of a call started by another one. Callers which gave
+	// up waiting, or which got the context error of a call whose own caller gave up, are not counted
+	Coalesced uint64
+}
+
+// call is a function run by a Group, whose result is available once done is closed
+type call struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	// waiters is the number of callers which joined the call, guarded by the mutex of the Group
+	waiters int
+}
+
+// Do runs fn and returns its result, unless a call with the same key is already running, in which case it waits for
+// it and returns its result instead. shared reports whether the result was obtained by another caller. If ctx is done
+// while waiting, ctx.Err() is returned, although the call keeps running for the rest of the callers
+func (g *Group) Do(ctx context.Context, key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mtx.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+
+	if c, found := g.calls[key]; found {
+		c.waiters++
+		g.mtx.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err(), true
+		}
+
+		// A context error only means the caller running the call gave up, so its result is not really shared
+		if !errors.Is(c.err, context.Canceled) && !errors.Is(c.err, context.DeadlineExceeded) {
+			g.mtx.Lock()
+			g.stats.Coalesced++
+			g.mtx.Unlock()
+		}
+
+		return c.val, c.err, true
+	}
+
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.stats.Calls++
+	g.mtx.Unlock()
+
+	c.val, c.err = fn()
+
+	g.mtx.Lock()
+	delete(g.calls, key)
+	g.mtx.Unlock()
+	close(c.done)
+
+	return c.val, c.err, false
+}
+
+// Stats returns the number of calls run and coalesced so far
+func (g *Group) Stats() GroupStats {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.stats
+}
@@ -0,0 +1,148 @@
+package lodestone
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupDo(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		callers   int
+		err       error
+		coalesced uint64
+	}{
+		{name: "single", callers: 1},
+		{name: "coalesced", callers: 5, coalesced: 4},
+		{name: "coalesced error", callers: 3, err: errors.New("lodestone is down"), coalesced: 2},
+		{name: "first caller cancelled", callers: 3, err: context.Canceled},
+	} {
+		g := &Group{}
+		release := make(chan struct{})
+		started := make(chan struct{})
+		runs := 0
+
+		fn := func() (interface{}, error) {
+			runs++
+			close(started)
+			<-release
+			return "result", tc.err
+		}
+
+		type result struct {
+			v      interface{}
+			err    error
+			shared bool
+		}
+		results := make(chan result, tc.callers)
+
+		// The first caller must be running fn before the rest join it
+		go func() {
+			v, err, shared := g.Do(context.Background(), "key", fn)
+			results <- result{v, err, shared}
+		}()
+		<-started
+
+		for i := 1; i < tc.callers; i++ {
+			go func() {
+				v, err, shared := g.Do(context.Background(), "key", fn)
+				results <- result{v, err, shared}
+			}()
+		}
+		waitForWaiters(g, "key", tc.callers-1)
+		close(release)
+
+		shared := 0
+		for i := 0; i < tc.callers; i++ {
+			r := <-results
+			if r.v != "result" || r.err != tc.err {
+				t.Errorf("%s: expected result and %v, got %v and %v", tc.name, tc.err, r.v, r.err)
+			}
+			if r.shared {
+				shared++
+			}
+		}
+
+		if runs != 1 {
+			t.Errorf("%s: expected fn to run once, ran %d times", tc.name, runs)
+		}
+		if shared != tc.callers-1 {
+			t.Errorf("%s: expected %d shared results, got %d", tc.name, tc.callers-1, shared)
+		}
+		if stats := g.Stats(); stats.Calls != 1 || stats.Coalesced != tc.coalesced {
+			t.Errorf("%s: unexpected stats %+v", tc.name, stats)
+		}
+	}
+}
+
+func TestGroupDoWaiterCancelled(t *testing.T) {
+	g := &Group{}
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	first := make(chan interface{})
+	go func() {
+		v, _, _ := g.Do(context.Background(), "key", func() (interface{}, error) {
+			close(started)
+			<-release
+			return "result", nil
+		})
+		first <- v
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := make(chan error)
+	go func() {
+		_, err, shared := g.Do(ctx, "key", func() (interface{}, error) {
+			t.Error("waiter should not run its own call")
+			return nil, nil
+		})
+		if !shared {
+			t.Error("expected cancelled waiter to report a shared call")
+		}
+		waiter <- err
+	}()
+	waitForWaiters(g, "key", 1)
+
+	// The waiter gives up, but the call keeps running for the first caller
+	cancel()
+	if err := <-waiter; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected waiter to get %v, got %v", context.Canceled, err)
+	}
+
+	close(release)
+	if v := <-first; v != "result" {
+		t.Errorf("expected first caller to get its result, got %v", v)
+	}
+
+	// The waiter did not use the result, so it is not counted as coalesced
+	if stats := g.Stats(); stats.Calls != 1 || stats.Coalesced != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// Once done, the key is free for new calls
+	v, _, shared := g.Do(context.Background(), "key", func() (interface{}, error) {
+		return "new result", nil
+	})
+	if v != "new result" || shared {
+		t.Errorf("expected a new call, got %v (shared: %v)", v, shared)
+	}
+}
+
+// waitForWaiters waits until n callers of g have joined the running call for key
+func waitForWaiters(g *Group, key string, n int) {
+	for {
+		g.mtx.Lock()
+		c, found := g.calls[key]
+		joined := found && c.waiters >= n
+		g.mtx.Unlock()
+
+		if joined {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
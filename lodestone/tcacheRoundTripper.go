@@ -3,21 +3,46 @@ package lodestone
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"roob.re/tcache"
+	"strconv"
+	"sync"
 	"time"
 )
 
-var errResponseNotOk = errors.New("not caching response as status code is >= 400")
+var errResponseNotOk = errors.New("not caching response as its status code is not cacheable")
 
+// storedAtHeader is added to cached responses to know their age when read back
+const storedAtHeader = "x-tcache-stored-at"
+
+// TCacheRoundTripper caches successful responses in a Cache, such as a tcache.Cache, DiskCache or RedisCache. Responses
+// younger than MaxAge are served from the cache. Older ones are still served for MaxStale more, while being refreshed
+// in the background, and keep being served if refreshing them fails. Requests waiting for a concurrent one to write the
+// same entry give up once their context is done, if Cache is a ContextCache
 type TCacheRoundTripper struct {
 	RoundTripper http.RoundTripper
-	Cache        *tcache.Cache
+	Cache        Cache
 	MaxAge       time.Duration
+	// MaxStale is the time after MaxAge during which stale responses are served. Zero disables serving stale responses
+	MaxStale time.Duration
+	// Policy overrides MaxAge for the paths matching any of its rules, and may enable caching 404 responses for them
+	Policy CachePolicy
+
+	// refreshing holds the keys being refreshed in the background, so each one is refreshed only once at a time
+	refreshing sync.Map
+}
+
+// rule returns the cache rule applying to a request path
+func (trt *TCacheRoundTripper) rule(path string) CacheRule {
+	if rule, found := trt.Policy.Rule(path); found {
+		return rule
+	}
+	return CacheRule{MaxAge: trt.MaxAge}
 }
 
 func (trt *TCacheRoundTripper) RoundTrip(rq *http.Request) (response *http.Response, err error) {
@@ -25,48 +50,172 @@ func (trt *TCacheRoundTripper) RoundTrip(rq *http.Request) (response *http.Respo
 		return trt.roundTrip(rq)
 	}
 
+	// Custom servers may pick the page language from the header alone, so it must be part of the key
 	url := rq.URL.String()
+	if lang := rq.Header.Get("accept-language"); lang != "" {
+		url += " " + lang
+	}
 
 	logpath := rq.URL.Path
 	if rq.URL.RawQuery != "" {
 		logpath += "?" + rq.URL.RawQuery
 	}
 
-	err = trt.Cache.Access(url, trt.MaxAge, tcache.Handler{
-		Then: func(r io.Reader) error {
-			log.Debug("hit " + logpath)
-			response, err = http.ReadResponse(bufio.NewReader(r), nil)
-			return err
-		},
-		Else: func(w io.Writer) error {
-			log.Debug("miss " + logpath)
+	rule := trt.rule(rq.URL.Path)
 
-			response, err = trt.roundTrip(rq)
+	// The status code of cached responses is not known until read, so the longest max age is used to look them up
+	maxAge := rule.MaxAge
+	if rule.NotFoundMaxAge > maxAge {
+		maxAge = rule.NotFoundMaxAge
+	}
+
+	stale, expired := false, false
+	err = accessContext(rq.Context(), trt.Cache, url, maxAge+trt.MaxStale, tcache.Handler{
+		Then: func(r io.Reader) error {
+			response, err = readResponse(r)
 			if err != nil {
 				return err
 			}
 
-			// Do not cache errored responses
-			if response.StatusCode >= 400 {
-				return errResponseNotOk
+			age, statusMaxAge := responseAge(response), rule.maxAge(response.StatusCode)
+			switch {
+			case age > statusMaxAge+trt.MaxStale:
+				expired = true
+			case age > statusMaxAge:
+				stale = true
+				log.Debug("stale hit " + logpath)
+			default:
+				log.Debug("hit " + logpath)
 			}
+			return nil
+		},
+		Else: func(w io.Writer) error {
+			log.Debug("miss " + logpath)
 
-			newBody := &bytes.Buffer{}
-			origBody := response.Body
-			response.Body = ioutil.NopCloser(io.TeeReader(origBody, newBody))
-			err = response.Write(w)
-			_ = origBody.Close()
-			response.Body = ioutil.NopCloser(newBody)
+			response, err = trt.store(rq, rule, w)
 			return err
 		},
 	})
 
+	// Responses which expired according to their status code, but not to the longest max age, are replaced right away
+	if expired {
+		log.Debug("expired " + logpath)
+		_ = response.Body.Close()
+		response = nil
+
+		// A concurrent access may have replaced the entry in the meantime, in which case it is served instead
+		err = accessContext(rq.Context(), trt.Cache, url, 0, tcache.Handler{
+			Then: func(r io.Reader) error {
+				response, err = readResponse(r)
+				if err != nil {
+					return err
+				}
+
+				response.Header.Del(storedAtHeader)
+				return nil
+			},
+			Else: func(w io.Writer) error {
+				response, err = trt.store(rq, rule, w)
+				return err
+			},
+		})
+	}
+
+	if stale {
+		go trt.refresh(rq, rule, url, logpath)
+	}
+
 	if err == errResponseNotOk {
 		return response, nil
 	}
 	return response, err
 }
 
+// refresh replaces a stale cached response with a new one in the background. The stale response is kept if the
+// lodestone fails to provide a new one
+func (trt *TCacheRoundTripper) refresh(rq *http.Request, rule CacheRule, url, logpath string) {
+	if _, loaded := trt.refreshing.LoadOrStore(url, struct{}{}); loaded {
+		return
+	}
+	defer trt.refreshing.Delete(url)
+
+	// The original request is likely to be done by the time this one is made
+	rq = rq.Clone(context.Background())
+
+	// Zero max age makes every entry expired, so the new response is always written
+	err := accessContext(rq.Context(), trt.Cache, url, 0, tcache.Handler{
+		Then: func(r io.Reader) error {
+			return nil
+		},
+		Else: func(w io.Writer) error {
+			response, err := trt.store(rq, rule, w)
+			if response != nil {
+				_ = response.Body.Close()
+			}
+			return err
+		},
+	})
+
+	if err != nil {
+		log.Warnf("could not refresh %s, serving stale response: %v", logpath, err)
+		return
+	}
+	log.Debug("refreshed " + logpath)
+}
+
+// store performs rq and writes the response to w if its status code is cacheable according to rule
+func (trt *TCacheRoundTripper) store(rq *http.Request, rule CacheRule, w io.Writer) (*http.Response, error) {
+	response, err := trt.roundTrip(rq)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rule.cacheable(response.StatusCode) {
+		return response, errResponseNotOk
+	}
+
+	response.Header.Set(storedAtHeader, strconv.FormatInt(time.Now().Unix(), 10))
+
+	newBody := &bytes.Buffer{}
+	origBody := response.Body
+	response.Body = ioutil.NopCloser(io.TeeReader(origBody, newBody))
+	err = response.Write(w)
+	_ = origBody.Close()
+	response.Body = ioutil.NopCloser(newBody)
+	response.Header.Del(storedAtHeader)
+	return response, err
+}
+
+// readResponse parses a cached response, reading its body right away as caches such as DiskCache close the reader once
+// Then returns
+func readResponse(r io.Reader) (*http.Response, error) {
+	response, err := http.ReadResponse(bufio.NewReader(r), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// responseAge returns how long ago a cached response was stored, removing the header it was obtained from. Responses
+// stored without it are considered fresh
+func responseAge(response *http.Response) time.Duration {
+	storedAt, err := strconv.ParseInt(response.Header.Get(storedAtHeader), 10, 64)
+	response.Header.Del(storedAtHeader)
+	if err != nil {
+		return 0
+	}
+
+	return time.Since(time.Unix(storedAt, 0))
+}
+
 func (trt *TCacheRoundTripper) roundTrip(r *http.Request) (response *http.Response, err error) {
 	return trt.RoundTripper.RoundTrip(r)
 }
@@ -0,0 +1,482 @@
+package lodestone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"roob.re/tcache"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memCache is an in-memory Cache whose entries can be made older
+type memCache struct {
+	mtx     sync.Mutex
+	entries map[string]memEntry
+}
+
+type memEntry struct {
+	data     []byte
+	storedAt time.Time
+}
+
+func newMemCache() *memCache {
+	return &memCache{entries: map[string]memEntry{}}
+}
+
+func (mc *memCache) Access(key string, maxAge time.Duration, handler tcache.Handler) error {
+	mc.mtx.Lock()
+	entry, found := mc.entries[key]
+	mc.mtx.Unlock()
+
+	if found && maxAge > 0 && time.Since(entry.storedAt) <= maxAge {
+		return handler.Then(bytes.NewReader(entry.data))
+	}
+
+	buf := &bytes.Buffer{}
+	if err := handler.Else(buf); err != nil {
+		return err
+	}
+
+	mc.mtx.Lock()
+	mc.entries[key] = memEntry{data: buf.Bytes(), storedAt: time.Now()}
+	mc.mtx.Unlock()
+	return nil
+}
+
+// storedAtRegex finds the time responses were stored at in cached entries
+var storedAtRegex = regexp.MustCompile(`(?i)(` + storedAtHeader + `: )(\d+)`)
+
+// age makes every entry, and the responses stored in them, older by d
+func (mc *memCache) age(d time.Duration) {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+
+	for key, entry := range mc.entries {
+		entry.storedAt = entry.storedAt.Add(-d)
+		entry.data = storedAtRegex.ReplaceAllFunc(entry.data, func(header []byte) []byte {
+			matches := storedAtRegex.FindSubmatch(header)
+			storedAt, _ := strconv.ParseInt(string(matches[2]), 10, 64)
+			return []byte(string(matches[1]) + strconv.FormatInt(storedAt-int64(d.Seconds()), 10))
+		})
+		mc.entries[key] = entry
+	}
+}
+
+// fakeLodestone answers requests with the status code and body it is set to, counting them
+type fakeLodestone struct {
+	mtx        sync.Mutex
+	statusCode int
+	body       string
+	err        error
+	requests   int
+}
+
+func (fl *fakeLodestone) set(statusCode int, body string, err error) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
+	fl.statusCode, fl.body, fl.err = statusCode, body, err
+}
+
+func (fl *fakeLodestone) count() int {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
+	return fl.requests
+}
+
+func (fl *fakeLodestone) RoundTrip(rq *http.Request) (*http.Response, error) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
+
+	fl.requests++
+	if fl.err != nil {
+		return nil, fl.err
+	}
+
+	return &http.Response{
+		StatusCode: fl.statusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(fl.body)),
+		Request:    rq,
+	}, nil
+}
+
+// cacheStep is a request made through a TCacheRoundTripper in a test
+type cacheStep struct {
+	name string
+	// age makes cached entries older before the request
+	age time.Duration
+	// statusCode, body and err set the answer of the lodestone
+	statusCode int
+	body       string
+	err        error
+
+	expectedStatus int
+	expectedBody   string
+	expectedErr    bool
+	// lodestoneRequests is the number of requests expected to reach the lodestone, including background refreshes
+	lodestoneRequests int
+}
+
+// runCacheSteps makes the requests described by steps, in order, checking their responses
+func runCacheSteps(t *testing.T, trt *TCacheRoundTripper, cache *memCache, lodestone *fakeLodestone, url string, steps []cacheStep) {
+	t.Helper()
+
+	for _, step := range steps {
+		cache.age(step.age)
+		lodestone.set(step.statusCode, step.body, step.err)
+
+		rq, _ := http.NewRequest(http.MethodGet, url, nil)
+		response, err := trt.RoundTrip(rq)
+		if step.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", step.name)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", step.name, err)
+		} else {
+			body, _ := ioutil.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if response.StatusCode != step.expectedStatus || string(body) != step.expectedBody {
+				t.Errorf("%s: expected %d %q, got %d %q",
+					step.name, step.expectedStatus, step.expectedBody, response.StatusCode, body)
+			}
+			if response.Header.Get(storedAtHeader) != "" {
+				t.Errorf("%s: internal header leaked into the response", step.name)
+			}
+		}
+
+		waitForRefreshes(trt, lodestone, step.lodestoneRequests)
+		if requests := lodestone.count(); requests != step.lodestoneRequests {
+			t.Errorf("%s: expected %d lodestone requests, got %d", step.name, step.lodestoneRequests, requests)
+		}
+	}
+}
+
+// waitForRefreshes waits for background refreshes to be done, giving up if the lodestone does not receive the
+// expected number of requests in time
+func waitForRefreshes(trt *TCacheRoundTripper, lodestone *fakeLodestone, requests int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		refreshing := false
+		trt.refreshing.Range(func(key, value interface{}) bool {
+			refreshing = true
+			return false
+		})
+
+		if !refreshing && lodestone.count() >= requests {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTCacheRoundTripperStale(t *testing.T) {
+	cache, lodestone := newMemCache(), &fakeLodestone{}
+	trt := &TCacheRoundTripper{
+		RoundTripper: lodestone,
+		Cache:        cache,
+		MaxAge:       10 * time.Minute,
+		MaxStale:     time.Hour,
+	}
+
+	runCacheSteps(t, trt, cache, lodestone, "https://eu.finalfantasyxiv.com/lodestone/character/1/", []cacheStep{
+		{
+			name: "miss", statusCode: http.StatusOK, body: "v1",
+			expectedStatus: http.StatusOK, expectedBody: "v1", lodestoneRequests: 1,
+		},
+		{
+			name: "hit", statusCode: http.StatusOK, body: "v2",
+			expectedStatus: http.StatusOK, expectedBody: "v1", lodestoneRequests: 1,
+		},
+		{
+			name: "stale", age: 20 * time.Minute, statusCode: http.StatusOK, body: "v2",
+			expectedStatus: http.StatusOK, expectedBody: "v1", lodestoneRequests: 2,
+		},
+		{
+			name: "refreshed", statusCode: http.StatusOK, body: "v3",
+			expectedStatus: http.StatusOK, expectedBody: "v2", lodestoneRequests: 2,
+		},
+		{
+			name: "stale while lodestone fails", age: 20 * time.Minute, err: errors.New("connection reset"),
+			expectedStatus: http.StatusOK, expectedBody: "v2", lodestoneRequests: 3,
+		},
+		{
+			name: "stale while lodestone is unavailable", statusCode: http.StatusServiceUnavailable, body: "maintenance",
+			expectedStatus: http.StatusOK, expectedBody: "v2", lodestoneRequests: 4,
+		},
+		{
+			name: "too stale", age: 2 * time.Hour, statusCode: http.StatusOK, body: "v5",
+			expectedStatus: http.StatusOK, expectedBody: "v5", lodestoneRequests: 5,
+		},
+		{
+			name: "too stale while lodestone fails", age: 2 * time.Hour, err: errors.New("connection reset"),
+			expectedErr: true, lodestoneRequests: 6,
+		},
+	})
+}
+
+func TestTCacheRoundTripperNotFound(t *testing.T) {
+	cache, lodestone := newMemCache(), &fakeLodestone{}
+	trt := &TCacheRoundTripper{
+		RoundTripper: lodestone,
+		Cache:        cache,
+		MaxAge:       10 * time.Minute,
+		Policy: CachePolicy{
+			{Pattern: regexp.MustCompile(`^/lodestone/character/\d+/$`), MaxAge: 10 * time.Minute, NotFoundMaxAge: 5 * time.Minute},
+		},
+	}
+
+	runCacheSteps(t, trt, cache, lodestone, "https://eu.finalfantasyxiv.com/lodestone/character/1/", []cacheStep{
+		{
+			name: "not found", statusCode: http.StatusNotFound, body: "not found",
+			expectedStatus: http.StatusNotFound, expectedBody: "not found", lodestoneRequests: 1,
+		},
+		{
+			name: "cached not found", statusCode: http.StatusOK, body: "v1",
+			expectedStatus: http.StatusNotFound, expectedBody: "not found", lodestoneRequests: 1,
+		},
+		{
+			// Still within the max age of successful responses, but not within the one of 404 responses
+			name: "expired not found", age: 7 * time.Minute, statusCode: http.StatusOK, body: "v1",
+			expectedStatus: http.StatusOK, expectedBody: "v1", lodestoneRequests: 2,
+		},
+		{
+			name: "hit", age: 7 * time.Minute, statusCode: http.StatusOK, body: "v2",
+			expectedStatus: http.StatusOK, expectedBody: "v1", lodestoneRequests: 2,
+		},
+		{
+			name: "miss while lodestone is unavailable", age: 7 * time.Minute, statusCode: http.StatusServiceUnavailable, body: "maintenance",
+			expectedStatus: http.StatusServiceUnavailable, expectedBody: "maintenance", lodestoneRequests: 3,
+		},
+	})
+
+	// Paths without a rule for 404 responses do not cache them
+	runCacheSteps(t, trt, cache, lodestone, "https://eu.finalfantasyxiv.com/lodestone/freecompany/1/", []cacheStep{
+		{
+			name: "uncached not found", statusCode: http.StatusNotFound, body: "not found",
+			expectedStatus: http.StatusNotFound, expectedBody: "not found", lodestoneRequests: 4,
+		},
+		{
+			name: "uncached not found again", statusCode: http.StatusNotFound, body: "not found",
+			expectedStatus: http.StatusNotFound, expectedBody: "not found", lodestoneRequests: 5,
+		},
+	})
+}
+
+func TestTCacheRoundTripperKey(t *testing.T) {
+	cache, lodestone := newMemCache(), &fakeLodestone{}
+	trt := &TCacheRoundTripper{RoundTripper: lodestone, Cache: cache, MaxAge: 10 * time.Minute}
+	lodestone.set(http.StatusOK, "page", nil)
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		language string
+		requests int
+	}{
+		{name: "english", method: http.MethodGet, language: "en-us", requests: 1},
+		{name: "english again", method: http.MethodGet, language: "en-us", requests: 1},
+		{name: "japanese", method: http.MethodGet, language: "ja", requests: 2},
+		{name: "post", method: http.MethodPost, language: "en-us", requests: 3},
+	} {
+		rq, _ := http.NewRequest(tc.method, "https://eu.finalfantasyxiv.com/lodestone/character/1/", nil)
+		rq.Header.Set("accept-language", tc.language)
+
+		response, err := trt.RoundTrip(rq)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		_ = response.Body.Close()
+
+		if requests := lodestone.count(); requests != tc.requests {
+			t.Errorf("%s: expected %d lodestone requests, got %d", tc.name, tc.requests, requests)
+		}
+	}
+}
+
+func TestTCacheRoundTripperDiskCache(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lodestone pages are larger than what is buffered while parsing the cached response
+	page := strings.Repeat("lodestone ", 2000)
+	lodestone := &fakeLodestone{}
+	trt := &TCacheRoundTripper{RoundTripper: lodestone, Cache: dc, MaxAge: 10 * time.Minute}
+
+	for _, tc := range []struct {
+		name     string
+		requests int
+	}{
+		{name: "miss", requests: 1},
+		{name: "hit", requests: 1},
+	} {
+		lodestone.set(http.StatusOK, page, nil)
+
+		rq, _ := http.NewRequest(http.MethodGet, "https://eu.finalfantasyxiv.com/lodestone/character/1/", nil)
+		response, err := trt.RoundTrip(rq)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+
+		if err != nil || string(body) != page {
+			t.Errorf("%s: expected the whole page, got %d bytes and %v", tc.name, len(body), err)
+		}
+		if requests := lodestone.count(); requests != tc.requests {
+			t.Errorf("%s: expected %d lodestone requests, got %d", tc.name, tc.requests, requests)
+		}
+	}
+}
+
+func TestTCacheRoundTripperConcurrentExpired(t *testing.T) {
+	const url = "https://eu.finalfantasyxiv.com/lodestone/character/1/"
+
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		cache Cache
+	}{
+		{name: "disk", cache: dc},
+		{name: "redis", cache: NewRedisCache(newFakeRedis(t).addr(), 2)},
+	} {
+		// A successful response which is expired according to its status code, but not to the one of 404 responses
+		storedAt := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+		accessString(t, tc.cache, url, time.Hour,
+			"HTTP/1.1 200 OK\r\n"+storedAtHeader+": "+storedAt+"\r\nContent-Length: 3\r\n\r\nold")
+
+		release := make(chan struct{})
+		requesting := make(chan struct{})
+		requests := 0
+		lodestone := roundTripperFunc(func(rq *http.Request) (*http.Response, error) {
+			requests++
+			close(requesting)
+			<-release
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader("new")),
+				Request:    rq,
+			}, nil
+		})
+
+		trt := &TCacheRoundTripper{
+			RoundTripper: lodestone,
+			Cache:        tc.cache,
+			Policy: CachePolicy{
+				{Pattern: regexp.MustCompile(`^/lodestone/character/\d+/$`), MaxAge: time.Second, NotFoundMaxAge: time.Hour},
+			},
+		}
+
+		bodies := make(chan string, 2)
+		get := func() {
+			rq, _ := http.NewRequest(http.MethodGet, url, nil)
+			response, err := trt.RoundTrip(rq)
+			if err != nil {
+				bodies <- err.Error()
+				return
+			}
+			body, err := ioutil.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if err != nil {
+				bodies <- err.Error()
+				return
+			}
+			bodies <- string(body)
+		}
+
+		// The second request waits for the first one to replace the expired entry, and serves the new one
+		go get()
+		<-requesting
+		go get()
+
+		time.Sleep(2 * lockPoll)
+		close(release)
+
+		for i := 0; i < 2; i++ {
+			if body := <-bodies; body != "new" {
+				t.Errorf("%s: expected the new response, got %q", tc.name, body)
+			}
+		}
+		if requests != 1 {
+			t.Errorf("%s: expected 1 lodestone request, got %d", tc.name, requests)
+		}
+	}
+}
+
+func TestTCacheRoundTripperCancelled(t *testing.T) {
+	dc, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	requesting := make(chan struct{})
+	lodestone := roundTripperFunc(func(rq *http.Request) (*http.Response, error) {
+		close(requesting)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("page")),
+			Request:    rq,
+		}, nil
+	})
+	trt := &TCacheRoundTripper{RoundTripper: lodestone, Cache: dc, MaxAge: 10 * time.Minute}
+
+	const url = "https://eu.finalfantasyxiv.com/lodestone/character/1/"
+	first := make(chan error)
+	go func() {
+		rq, _ := http.NewRequest(http.MethodGet, url, nil)
+		response, err := trt.RoundTrip(rq)
+		if err == nil {
+			_ = response.Body.Close()
+		}
+		first <- err
+	}()
+	<-requesting
+
+	// The second request waits for the first one to write the entry, until it is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	rq, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if _, err := trt.RoundTrip(rq); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected request to give up once cancelled, took %v", elapsed)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
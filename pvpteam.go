@@ -0,0 +1,83 @@
+package ffxivapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"time"
+)
+
+// PvPTeam models FFXIV PvP team data
+type PvPTeam struct {
+	ParsedAt time.Time
+
+	ID         string
+	Name       string
+	DataCenter string
+	Region     string
+	// Crest holds the URLs of the images which, stacked in order, compose the team crest
+	Crest  []string
+	Formed time.Time
+
+	Members []PvPTeamMember
+}
+
+// PvPTeamMember is a character listed in the member list of a PvP team, alongside their Feast stats
+type PvPTeamMember struct {
+	ID         int
+	Name       string
+	Avatar     string
+	World      string
+	DataCenter string
+	Region     string
+
+	FeastMatches  int
+	FeastRankIcon string
+}
+
+// PvPTeam returns PvP team data given its ID
+func (api *FFXIVAPI) PvPTeam(id string) (*PvPTeam, error) {
+	return api.PvPTeamContext(context.Background(), id)
+}
+
+// PvPTeamContext behaves like PvPTeam, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) PvPTeamContext(ctx context.Context, id string) (*PvPTeam, error) {
+	doc, err := api.lodestone(ctx, fmt.Sprintf("/lodestone/pvpteam/%s/", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &PvPTeam{ID: id, ParsedAt: time.Now()}
+
+	team.Name = strings.TrimSpace(doc.Find(".entry__pvpteam__name--team").First().Text())
+	team.DataCenter = strings.TrimSpace(doc.Find(".entry__pvpteam__name--dc").First().Text())
+	team.Region = DataCenterRegion(team.DataCenter)
+	team.Crest = crestLayers(doc.Selection, ".entry__pvpteam__crest__image")
+	team.Formed = lodestoneTime(doc.Find(".entry__pvpteam__data--formed"))
+
+	// PvP teams have at most 8 members, so the list is never paginated
+	doc.Find("li.entry > a.entry__bg").Each(func(i int, sel *goquery.Selection) {
+		matches := urlIdRegex.FindStringSubmatch(sel.AttrOr("href", ""))
+		if len(matches) < 2 {
+			return
+		}
+
+		// Feast info holds the rank icon first and the number of matches played last
+		feast := sel.Find(".entry__freecompany__info > li")
+		world := splitWorld(sel.Find(".entry__world").First().Text())
+
+		team.Members = append(team.Members, PvPTeamMember{
+			ID:            silentAtoi(matches[1]),
+			Name:          strings.TrimSpace(sel.Find(".entry__name").First().Text()),
+			Avatar:        sel.Find(".entry__chara__face > img").First().AttrOr("src", ""),
+			World:         world.Name,
+			DataCenter:    world.DataCenter,
+			Region:        world.Region,
+			FeastMatches:  silentAtoi(strings.TrimSpace(feast.Last().Find("span").First().Text())),
+			FeastRankIcon: feast.First().Find("img").First().AttrOr("src", ""),
+		})
+	})
+
+	return team, nil
+}
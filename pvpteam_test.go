@@ -0,0 +1,70 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+	"time"
+)
+
+func TestPvPTeam(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/pvpteam/f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9/": "pvpteam.html",
+	}}
+
+	for _, tc := range []struct {
+		name     string
+		id       string
+		expected *PvPTeam
+		err      error
+	}{
+		{
+			name: "team",
+			id:   "f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9",
+			expected: &PvPTeam{
+				ID:         "f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9",
+				Name:       "Fun Wolves",
+				DataCenter: "Chaos",
+				Region:     GameRegionEU,
+				Crest: []string{
+					"https://img2.finalfantasyxiv.com/c/B2_a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5_00_128x128.png",
+					"https://img2.finalfantasyxiv.com/c/F3_b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6_04_128x128.png",
+				},
+				Formed: time.Unix(1546300800, 0),
+				Members: []PvPTeamMember{
+					{
+						ID: 31688528, Name: "Alyx Bergen", Avatar: "https://img2.finalfantasyxiv.com/f/alyx_96x96.jpg",
+						World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+						FeastMatches: 35, FeastRankIcon: "https://img.finalfantasyxiv.com/lds/h/9/gold.png",
+					},
+					{
+						ID: 2, Name: "Lyse Hext", Avatar: "https://img2.finalfantasyxiv.com/f/lyse_96x96.jpg",
+						World: "Omega", DataCenter: "Chaos", Region: GameRegionEU,
+						FeastRankIcon: "https://img.finalfantasyxiv.com/lds/h/C/unranked.png",
+					},
+				},
+			},
+		},
+		{name: "missing", id: "0", err: lodestone.HTTPError(404)},
+	} {
+		team, err := api.PvPTeamContext(context.Background(), tc.id)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		team.ParsedAt = time.Time{}
+		if !reflect.DeepEqual(team, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, team)
+		}
+	}
+}
@@ -1,29 +1,254 @@
 package ffxivapi
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type SearchResult struct {
-	ID     int
-	Level  int
-	Avatar string
-	Lang   string
-	Name   string
-	World  string
+	ID         int
+	Level      int
+	Avatar     string
+	Lang       string
+	Name       string
+	World      string
+	DataCenter string
+	Region     string
 }
 
+// Search looks for characters matching the given name in the given world
+// ErrUnknownWorld is returned, without querying the Lodestone, if world is not a known world
 func (api *FFXIVAPI) Search(characterName string, world string) ([]SearchResult, error) {
-	doc, err := api.lodestone("/lodestone/character/", map[string]string{
-		"q":         characterName,
-		"worldname": strings.Title(strings.ToLower(world)),
-	})
+	return api.SearchContext(context.Background(), characterName, world)
+}
+
+// SearchContext behaves like Search, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) SearchContext(ctx context.Context, characterName string, world string) ([]SearchResult, error) {
+	results, err := api.SearchPageContext(ctx, characterName, world, 1)
 	if err != nil {
 		return nil, err
 	}
 
+	return results.Results, nil
+}
+
+// SearchAllPages can be passed as page to SearchPage or SearchOptions to retrieve every page of results
+const SearchAllPages = -1
+
+// SearchResults is a page, or all of them, of character search results
+type SearchResults struct {
+	// Page is the number of the returned page, starting from 1, or SearchAllPages
+	Page int
+	// Pages is the number of pages of results available
+	Pages int
+	// Total is the number of results across all pages, as reported by the lodestone
+	Total   int
+	Results []SearchResult
+}
+
+// SearchPage behaves like Search, but returns the given page of results, or all of them if page is SearchAllPages
+// Pages are retrieved concurrently when requesting all of them
+func (api *FFXIVAPI) SearchPage(characterName string, world string, page int) (*SearchResults, error) {
+	return api.SearchPageContext(context.Background(), characterName, world, page)
+}
+
+// SearchPageContext behaves like SearchPage, aborting every Lodestone request if ctx is done
+func (api *FFXIVAPI) SearchPageContext(ctx context.Context, characterName string, world string, page int) (*SearchResults, error) {
+	return api.SearchWithOptionsContext(ctx, characterName, SearchOptions{World: world, Page: page})
+}
+
+// SearchOptions holds the location, filters, sorting and page of a character search. Empty fields are not filtered on
+type SearchOptions struct {
+	// World to search in. If empty, the whole DataCenter is searched instead
+	World      string
+	DataCenter string
+
+	// ClassJob is the abbreviation of a class or job, such as "pld" or "gla"
+	ClassJob string
+	// Race is one of "hyur", "elezen", "lalafell", "miqote", "roegadyn", "aura", "hrothgar" or "viera"
+	Race string
+	// Tribe is a clan, such as "midlander" or "seekerofthesun", and takes precedence over Race
+	Tribe string
+	// GC is one of "maelstrom", "twinadder" or "immortalflames"
+	GC string
+	// Language is the language set by the player, one of "ja", "en", "de" or "fr"
+	Language string
+	// Order is one of "name", "-name", "world", "-world", "level" or "-level", where "-" denotes descending order
+	Order string
+
+	// Page of results to return, starting from 1, or SearchAllPages. Defaults to the first page
+	Page int
+}
+
+// characterFilterValues maps the lodestone parameter for each character search filter to the values it accepts
+var characterFilterValues = map[string]map[string]string{
+	"classjob": {
+		"gla": "1", "pgl": "2", "mrd": "3", "lnc": "4", "arc": "5", "cnj": "6", "thm": "7",
+		"crp": "8", "bsm": "9", "arm": "10", "gsm": "11", "ltw": "12", "wvr": "13", "alc": "14", "cul": "15",
+		"min": "16", "btn": "17", "fsh": "18",
+		"pld": "19", "mnk": "20", "war": "21", "drg": "22", "brd": "23", "whm": "24", "blm": "25",
+		"acn": "26", "smn": "27", "sch": "28", "rog": "29", "nin": "30", "mch": "31", "drk": "32",
+		"ast": "33", "sam": "34", "rdm": "35", "blu": "36", "gnb": "37", "dnc": "38", "rpr": "39",
+		"sge": "40", "vpr": "41", "pct": "42",
+	},
+	"race": {
+		"hyur": "race_1", "elezen": "race_2", "lalafell": "race_3", "miqote": "race_4",
+		"roegadyn": "race_5", "aura": "race_6", "hrothgar": "race_7", "viera": "race_8",
+	},
+	"tribe": {
+		"midlander": "tribe_1", "highlander": "tribe_2", "wildwood": "tribe_3", "duskwight": "tribe_4",
+		"plainsfolk": "tribe_5", "dunesfolk": "tribe_6", "seekerofthesun": "tribe_7", "keeperofthemoon": "tribe_8",
+		"seawolf": "tribe_9", "hellsguard": "tribe_10", "raen": "tribe_11", "xaela": "tribe_12",
+		"helions": "tribe_13", "thelost": "tribe_14", "rava": "tribe_15", "veena": "tribe_16",
+	},
+	"gcid": fcFilterValues["gcid"],
+	"blog_lang": {
+		"ja": "ja", "en": "en", "de": "de", "fr": "fr",
+	},
+	"order": {
+		"name": "1", "-name": "2", "world": "3", "-world": "4", "-level": "5", "level": "6",
+	},
+}
+
+// params returns the lodestone parameters corresponding to the search location and filters
+func (o SearchOptions) params() (map[string]string, error) {
+	params := map[string]string{}
+
+	// Data center searches are expressed as a special world name
+	switch {
+	case o.World != "":
+		world, err := canonicalWorld(o.World)
+		if err != nil {
+			return nil, err
+		}
+		params["worldname"] = world
+	case o.DataCenter != "":
+		dc, err := canonicalDataCenter(o.DataCenter)
+		if err != nil {
+			return nil, err
+		}
+		params["worldname"] = "_dc_" + dc
+	default:
+		return nil, fmt.Errorf("%w: either world or data center must be specified", ErrInvalidFilter)
+	}
+
+	err := filterParams(characterFilterValues, map[string]string{
+		"classjob":  o.ClassJob,
+		"race":      o.Race,
+		"tribe":     o.Tribe,
+		"gcid":      o.GC,
+		"blog_lang": o.Language,
+		"order":     o.Order,
+	}, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Race and tribe are sent in the same parameter
+	if tribe, found := params["tribe"]; found {
+		params["race_tribe"] = tribe
+	} else if race, found := params["race"]; found {
+		params["race_tribe"] = race
+	}
+	delete(params, "tribe")
+	delete(params, "race")
+
+	return params, nil
+}
+
+// SearchWithOptions looks for characters matching the given name, narrowing down results with the given options
+// ErrUnknownWorld or ErrInvalidFilter are returned, without querying the Lodestone, if options are not valid
+func (api *FFXIVAPI) SearchWithOptions(characterName string, options SearchOptions) (*SearchResults, error) {
+	return api.SearchWithOptionsContext(context.Background(), characterName, options)
+}
+
+// SearchWithOptionsContext behaves like SearchWithOptions, aborting every Lodestone request if ctx is done
+func (api *FFXIVAPI) SearchWithOptionsContext(ctx context.Context, characterName string, options SearchOptions) (*SearchResults, error) {
+	params, err := options.params()
+	if err != nil {
+		return nil, err
+	}
+	params["q"] = characterName
+
+	dc := options.DataCenter
+	if info, found := LookupWorld(options.World); found {
+		dc = info.DataCenter
+	}
+	ctx = api.withDataCenterRegion(ctx, dc)
+
+	page := options.Page
+	if page == 0 {
+		page = 1
+	}
+
+	const query = "/lodestone/character/"
+
+	firstParams := params
+	if page > 1 {
+		firstParams = map[string]string{"page": fmt.Sprint(page)}
+		for k, v := range params {
+			firstParams[k] = v
+		}
+	}
+
+	doc, err := api.lodestone(ctx, query, firstParams)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{
+		Page:  page,
+		Pages: lastPage(doc),
+		Total: searchTotal(doc),
+	}
+
+	// The last page has no link to itself, so the pager of the requested page can point to an earlier one
+	if page > results.Pages {
+		results.Pages = page
+	}
+
+	if page != SearchAllPages {
+		results.Results = parseSearchPage(doc)
+		return results, nil
+	}
+
+	// Results are stored by page so they can be returned in the same order the lodestone lists them
+	pages := make([][]SearchResult, results.Pages+1)
+	var pageErr error
+
+	api.eachPage(ctx, query, params, doc, func(page int, doc *goquery.Document, err error) {
+		if err != nil {
+			pageErr = &FeatureError{Feature: FeatureNameSearch, Page: page, Err: err}
+			return
+		}
+
+		pages[page] = parseSearchPage(doc)
+	})
+
+	if pageErr != nil {
+		return nil, pageErr
+	}
+
+	results.Results = make([]SearchResult, 0, results.Total)
+	for _, p := range pages {
+		results.Results = append(results.Results, p...)
+	}
+
+	return results, nil
+}
+
+// searchTotal returns the total number of results of a search, as displayed in the page header, e.g. "126 Total"
+func searchTotal(doc *goquery.Document) int {
+	return firstNumber(doc.Find(".parts__total").First().Text())
+}
+
+// parseSearchPage returns the characters found in a page of search results
+func parseSearchPage(doc *goquery.Document) []SearchResult {
 	results := make([]SearchResult, 0, 1)
 
 	doc.Find("a.entry__link").Each(func(i int, sel *goquery.Selection) {
@@ -49,7 +274,10 @@ func (api *FFXIVAPI) Search(characterName string, world string) ([]SearchResult,
 		result.Avatar = imagesrc
 
 		result.Name = sel.Find("p.entry__name").First().Text()
-		result.World = sel.Find("p.entry__world").First().Text()
+		worldInfo := splitWorld(sel.Find("p.entry__world").First().Text())
+		result.World = worldInfo.Name
+		result.DataCenter = worldInfo.DataCenter
+		result.Region = worldInfo.Region
 		result.Lang = sel.Find(".entry__chara__lang").First().Text()
 
 		levelText := sel.Find(".entry__chara_info").First().Find("span").First().Text()
@@ -61,5 +289,165 @@ func (api *FFXIVAPI) Search(characterName string, world string) ([]SearchResult,
 		results = append(results, result)
 	})
 
+	return results
+}
+
+// ErrInvalidFilter is returned when a search filter is set to a value not understood by the Lodestone
+var ErrInvalidFilter = errors.New("invalid search filter")
+
+// FreeCompanySearchFilters narrows down free company searches. Empty fields are not filtered on
+type FreeCompanySearchFilters struct {
+	// GC is one of "maelstrom", "twinadder" or "immortalflames"
+	GC string
+	// ActiveTime is one of "always", "weekdays" or "weekends"
+	ActiveTime string
+	// Recruitment is either "open" or "closed"
+	Recruitment string
+	// House is one of "estate", "plot" or "none"
+	House string
+	// Focus is one of "roleplay", "leveling", "casual", "hardcore", "dungeons", "guildhests", "trials", "raids" or "pvp"
+	Focus string
+	// Members is one of the member count buckets: "1-10", "11-30", "31-50" or "51-"
+	Members string
+}
+
+// fcFilterValues maps the lodestone parameter for each filter to the values it accepts
+var fcFilterValues = map[string]map[string]string{
+	"gcid": {
+		"maelstrom":      "1",
+		"twinadder":      "2",
+		"immortalflames": "3",
+	},
+	"activetime": {
+		"always":   "1",
+		"weekdays": "2",
+		"weekends": "3",
+	},
+	"join": {
+		"open":   "1",
+		"closed": "0",
+	},
+	"house": {
+		"estate": "2",
+		"plot":   "1",
+		"none":   "0",
+	},
+	"activities": {
+		"roleplay":   "0",
+		"leveling":   "1",
+		"casual":     "2",
+		"hardcore":   "3",
+		"dungeons":   "4",
+		"guildhests": "5",
+		"trials":     "6",
+		"raids":      "7",
+		"pvp":        "8",
+	},
+	"character_count": {
+		"1-10":  "1-10",
+		"11-30": "11-30",
+		"31-50": "31-50",
+		"51-":   "51-",
+	},
+}
+
+// params adds the lodestone parameters corresponding to the set filters to the given map
+func (f FreeCompanySearchFilters) params(params map[string]string) error {
+	return filterParams(fcFilterValues, map[string]string{
+		"gcid":            f.GC,
+		"activetime":      f.ActiveTime,
+		"join":            f.Recruitment,
+		"house":           f.House,
+		"activities":      f.Focus,
+		"character_count": f.Members,
+	}, params)
+}
+
+// filterParams translates the non-empty filters, keyed by lodestone parameter, to the values the lodestone expects
+// according to table, and adds them to params
+func filterParams(table map[string]map[string]string, filters map[string]string, params map[string]string) error {
+	for param, value := range filters {
+		if value == "" {
+			continue
+		}
+
+		lodestoneValue, found := table[param][strings.ToLower(value)]
+		if !found {
+			return fmt.Errorf("%w: %q is not a valid value for %s", ErrInvalidFilter, value, param)
+		}
+
+		params[param] = lodestoneValue
+	}
+
+	return nil
+}
+
+// FreeCompanySearchResult is a free company as listed in the Lodestone search results
+type FreeCompanySearchResult struct {
+	ID         string
+	Name       string
+	Crest      []string
+	World      string
+	DataCenter string
+	Region     string
+	GC         string
+
+	ActiveMembers int
+	Formed        time.Time
+}
+
+// SearchFreeCompany looks for free companies matching the given name in the given world, optionally narrowing results down with filters
+func (api *FFXIVAPI) SearchFreeCompany(name string, world string, filters FreeCompanySearchFilters) ([]FreeCompanySearchResult, error) {
+	return api.SearchFreeCompanyContext(context.Background(), name, world, filters)
+}
+
+// SearchFreeCompanyContext behaves like SearchFreeCompany, aborting the Lodestone request if ctx is done
+func (api *FFXIVAPI) SearchFreeCompanyContext(ctx context.Context, name string, world string, filters FreeCompanySearchFilters) ([]FreeCompanySearchResult, error) {
+	world, err := canonicalWorld(world)
+	if err != nil {
+		return nil, err
+	}
+
+	info, _ := LookupWorld(world)
+	ctx = api.withDataCenterRegion(ctx, info.DataCenter)
+
+	params := map[string]string{
+		"q":         name,
+		"worldname": world,
+	}
+	if err := filters.params(params); err != nil {
+		return nil, err
+	}
+
+	doc, err := api.lodestone(ctx, "/lodestone/freecompany/", params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]FreeCompanySearchResult, 0, 1)
+
+	doc.Find(".entry > a.entry__block").Each(func(i int, sel *goquery.Selection) {
+		matches := urlIdRegex.FindStringSubmatch(sel.AttrOr("href", ""))
+		if len(matches) < 2 {
+			return
+		}
+
+		// Both the grand company and the world use the same class, in that order
+		worlds := sel.Find(".entry__world")
+		world := splitWorld(worlds.Last().Text())
+
+		results = append(results, FreeCompanySearchResult{
+			ID:            matches[1],
+			Name:          strings.TrimSpace(sel.Find(".entry__name").First().Text()),
+			Crest:         crestLayers(sel, ".entry__freecompany__crest__image"),
+			World:         world.Name,
+			DataCenter:    world.DataCenter,
+			Region:        world.Region,
+			GC:            strings.TrimSpace(worlds.First().Text()),
+			ActiveMembers: silentAtoi(strings.TrimSpace(sel.Find(".entry__freecompany__fc-member").First().Text())),
+			Formed:        lodestoneTime(sel.Find(".entry__freecompany__fc-day")),
+		})
+	})
+
 	return results, nil
 }
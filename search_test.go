@@ -0,0 +1,294 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"reflect"
+	"roob.re/ffxivapi/lodestone"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchOptionsParams(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		options  SearchOptions
+		expected map[string]string
+		err      error
+	}{
+		{
+			name:     "world",
+			options:  SearchOptions{World: "ragnarok"},
+			expected: map[string]string{"worldname": "Ragnarok"},
+		},
+		{
+			name:     "data center",
+			options:  SearchOptions{DataCenter: "chaos"},
+			expected: map[string]string{"worldname": "_dc_Chaos"},
+		},
+		{
+			name:     "world takes precedence",
+			options:  SearchOptions{World: "Ragnarok", DataCenter: "Light"},
+			expected: map[string]string{"worldname": "Ragnarok"},
+		},
+		{
+			name:    "no location",
+			options: SearchOptions{ClassJob: "pld"},
+			err:     ErrInvalidFilter,
+		},
+		{
+			name:    "unknown world",
+			options: SearchOptions{World: "Nowhere"},
+			err:     ErrUnknownWorld,
+		},
+		{
+			name:    "unknown data center",
+			options: SearchOptions{DataCenter: "Nowhere"},
+			err:     ErrUnknownWorld,
+		},
+		{
+			name: "filters",
+			options: SearchOptions{
+				World: "Ragnarok", ClassJob: "PLD", GC: "twinadder", Language: "ja", Order: "-level",
+			},
+			expected: map[string]string{
+				"worldname": "Ragnarok", "classjob": "19", "gcid": "2", "blog_lang": "ja", "order": "5",
+			},
+		},
+		{
+			name:     "race",
+			options:  SearchOptions{World: "Ragnarok", Race: "miqote"},
+			expected: map[string]string{"worldname": "Ragnarok", "race_tribe": "race_4"},
+		},
+		{
+			name:     "tribe",
+			options:  SearchOptions{World: "Ragnarok", Tribe: "SeekerOfTheSun"},
+			expected: map[string]string{"worldname": "Ragnarok", "race_tribe": "tribe_7"},
+		},
+		{
+			name:     "tribe takes precedence over race",
+			options:  SearchOptions{World: "Ragnarok", Race: "hyur", Tribe: "xaela"},
+			expected: map[string]string{"worldname": "Ragnarok", "race_tribe": "tribe_12"},
+		},
+		{
+			name:    "invalid race",
+			options: SearchOptions{World: "Ragnarok", Race: "garlean"},
+			err:     ErrInvalidFilter,
+		},
+		{
+			name:    "invalid race with valid tribe",
+			options: SearchOptions{World: "Ragnarok", Race: "garlean", Tribe: "xaela"},
+			err:     ErrInvalidFilter,
+		},
+		{
+			name:    "invalid order",
+			options: SearchOptions{World: "Ragnarok", Order: "random"},
+			err:     ErrInvalidFilter,
+		},
+	} {
+		params, err := tc.options.params()
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v and %v", tc.name, tc.err, params, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(params, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, params)
+		}
+	}
+}
+
+func TestSearchWithOptions(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/character/?q=alyx&worldname=Ragnarok":         "character_search.html",
+		"/lodestone/character/?page=2&q=alyx&worldname=Ragnarok":  "character_search_2.html",
+		"/lodestone/character/?page=3&q=alyx&worldname=Ragnarok":  "character_search_3.html",
+		"/lodestone/character/?q=krile&worldname=Ragnarok":        "character_search.html",
+		"/lodestone/character/?page=2&q=krile&worldname=Ragnarok": "character_search_2.html",
+		"/lodestone/character/?q=nobody&worldname=Ragnarok":       "empty.html",
+	}}
+
+	characters := []SearchResult{
+		{
+			ID: 31688528, Level: 90, Avatar: "https://img2.finalfantasyxiv.com/f/alyx_96x96.jpg", Lang: "EN",
+			Name: "Alyx Bergen", World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+		},
+		{
+			ID: 2, Level: 80, Avatar: "https://img2.finalfantasyxiv.com/f/lyse_96x96.jpg", Lang: "EN",
+			Name: "Alyx Hext", World: "Omega", DataCenter: "Chaos", Region: GameRegionEU,
+		},
+		{
+			ID: 3, Level: 70, Avatar: "https://img2.finalfantasyxiv.com/f/tataru_96x96.jpg", Lang: "JA",
+			Name: "Alyx Taru", World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+		},
+		{
+			ID: 4, Level: 60, Avatar: "https://img2.finalfantasyxiv.com/f/krile_96x96.jpg", Lang: "DE",
+			Name: "Alyx Baldesion", World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+		},
+		{
+			ID: 5, Level: 50, Avatar: "https://img2.finalfantasyxiv.com/f/alisaie_96x96.jpg", Lang: "FR",
+			Name: "Alyx Leveilleur", World: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU,
+		},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		characterName string
+		page          int
+		expected      *SearchResults
+		err           error
+	}{
+		{
+			name: "default page", characterName: "alyx",
+			expected: &SearchResults{Page: 1, Pages: 3, Total: 5, Results: characters[:2]},
+		},
+		{
+			name: "second page", characterName: "alyx", page: 2,
+			expected: &SearchResults{Page: 2, Pages: 3, Total: 5, Results: characters[2:4]},
+		},
+		{
+			name: "last page", characterName: "alyx", page: 3,
+			expected: &SearchResults{Page: 3, Pages: 3, Total: 5, Results: characters[4:]},
+		},
+		{
+			name: "all pages", characterName: "alyx", page: SearchAllPages,
+			expected: &SearchResults{Page: SearchAllPages, Pages: 3, Total: 5, Results: characters},
+		},
+		{
+			name: "no results", characterName: "nobody", page: SearchAllPages,
+			expected: &SearchResults{Page: SearchAllPages, Pages: 1, Total: 0, Results: []SearchResult{}},
+		},
+		{
+			name: "missing page", characterName: "krile", page: SearchAllPages,
+			err: &FeatureError{Feature: FeatureNameSearch, Page: 3, Err: lodestone.HTTPError(404)},
+		},
+		{
+			name: "missing first page", characterName: "krile", page: 3,
+			err: lodestone.HTTPError(404),
+		},
+	} {
+		results, err := api.SearchWithOptionsContext(context.Background(), tc.characterName, SearchOptions{
+			World: "Ragnarok",
+			Page:  tc.page,
+		})
+		if tc.err != nil {
+			if err == nil || err.Error() != tc.err.Error() || results != nil {
+				t.Errorf("%s: expected %v, got %+v and %v", tc.name, tc.err, results, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(results, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, results)
+		}
+	}
+}
+
+func TestSearchTotal(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		html     string
+		expected int
+	}{
+		{name: "total", html: `<div class="parts__total">126 Total</div>`, expected: 126},
+		{name: "thousands separator", html: `<div class="parts__total">1,234 Total</div>`, expected: 1234},
+		{name: "first total", html: `<div class="parts__total">5 Total</div><div class="parts__total">9 Total</div>`, expected: 5},
+		{name: "missing", html: `<div class="ldst__contents"></div>`, expected: 0},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if total := searchTotal(doc); total != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, total)
+		}
+	}
+}
+
+func TestSearchFreeCompany(t *testing.T) {
+	api := &FFXIVAPI{Lodestone: fixtureLodestone{
+		"/lodestone/freecompany/?q=fun&worldname=Ragnarok":               "freecompany_search.html",
+		"/lodestone/freecompany/?gcid=1&join=1&q=fun&worldname=Ragnarok": "freecompany_search.html",
+		"/lodestone/freecompany/?q=nobody&worldname=Ragnarok":            "empty.html",
+	}}
+
+	results := []FreeCompanySearchResult{
+		{
+			ID:   "9237023573225362244",
+			Name: "Fun Company",
+			Crest: []string{
+				"https://img2.finalfantasyxiv.com/c/B0_0f9b1c6c0f0e6a8f0e8a7a2f5a8c4d3e_00_64x64.png",
+				"https://img2.finalfantasyxiv.com/c/F0_8e3d59a6a7e1a0b3f2c1d6e5b4a39281_02_64x64.png",
+			},
+			World:         "Ragnarok",
+			DataCenter:    "Chaos",
+			Region:        GameRegionEU,
+			GC:            "Maelstrom",
+			ActiveMembers: 42,
+			Formed:        time.Unix(1379512345, 0),
+		},
+		{
+			ID:            "9237023573225300001",
+			Name:          "Fun Times",
+			Crest:         []string{"https://img2.finalfantasyxiv.com/c/B1_5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a_00_64x64.png"},
+			World:         "Ragnarok",
+			DataCenter:    "Chaos",
+			Region:        GameRegionEU,
+			GC:            "Immortal Flames",
+			ActiveMembers: 3,
+			Formed:        time.Unix(1609459200, 0),
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		fcName   string
+		world    string
+		filters  FreeCompanySearchFilters
+		expected []FreeCompanySearchResult
+		err      error
+	}{
+		{name: "results", fcName: "fun", world: "ragnarok", expected: results},
+		{
+			name: "filters", fcName: "fun", world: "Ragnarok",
+			filters:  FreeCompanySearchFilters{GC: "Maelstrom", Recruitment: "open"},
+			expected: results,
+		},
+		{name: "no results", fcName: "nobody", world: "Ragnarok", expected: []FreeCompanySearchResult{}},
+		{name: "unknown world", fcName: "fun", world: "Nowhere", err: ErrUnknownWorld},
+		{
+			name: "invalid filter", fcName: "fun", world: "Ragnarok",
+			filters: FreeCompanySearchFilters{House: "castle"},
+			err:     ErrInvalidFilter,
+		},
+	} {
+		found, err := api.SearchFreeCompanyContext(context.Background(), tc.fcName, tc.world, tc.filters)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected %v, got %v and %v", tc.name, tc.err, found, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(found, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, found)
+		}
+	}
+}
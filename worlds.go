@@ -0,0 +1,167 @@
+package ffxivapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"roob.re/ffxivapi/lodestone"
+	"strings"
+)
+
+// ErrUnknownWorld is returned when a world or data center is not found in the built-in world table
+var ErrUnknownWorld = errors.New("unknown world or data center")
+
+// Game regions, to which data centers belong. They are not the same as lodestone regions, which are the subdomains the
+// lodestone is served from: see LodestoneRegion to obtain the one serving a game region
+const (
+	GameRegionNA  = "NA"
+	GameRegionEU  = "EU"
+	GameRegionJP  = "JP"
+	GameRegionOCE = "OCE"
+)
+
+// WorldInfo holds the data center and region a world belongs to
+type WorldInfo struct {
+	Name       string
+	DataCenter string
+	Region     string
+}
+
+// dataCenter is an entry of the built-in world table
+type dataCenter struct {
+	name   string
+	region string
+	worlds []string
+}
+
+// dataCenters lists every public data center and the worlds it hosts
+var dataCenters = []dataCenter{
+	{"Aether", GameRegionNA, []string{"Adamantoise", "Cactuar", "Faerie", "Gilgamesh", "Jenova", "Midgardsormr", "Sargatanas", "Siren"}},
+	{"Crystal", GameRegionNA, []string{"Balmung", "Brynhildr", "Coeurl", "Diabolos", "Goblin", "Malboro", "Mateus", "Zalera"}},
+	{"Dynamis", GameRegionNA, []string{"Cuchulainn", "Golem", "Halicarnassus", "Kraken", "Maduin", "Marilith", "Rafflesia", "Seraph"}},
+	{"Primal", GameRegionNA, []string{"Behemoth", "Excalibur", "Exodus", "Famfrit", "Hyperion", "Lamia", "Leviathan", "Ultros"}},
+	{"Chaos", GameRegionEU, []string{"Cerberus", "Louisoix", "Moogle", "Omega", "Phantom", "Ragnarok", "Sagittarius", "Spriggan"}},
+	{"Light", GameRegionEU, []string{"Alpha", "Lich", "Odin", "Phoenix", "Raiden", "Shiva", "Twintania", "Zodiark"}},
+	{"Elemental", GameRegionJP, []string{"Aegis", "Atomos", "Carbuncle", "Garuda", "Gungnir", "Kujata", "Tonberry", "Typhon"}},
+	{"Gaia", GameRegionJP, []string{"Alexander", "Bahamut", "Durandal", "Fenrir", "Ifrit", "Ridill", "Tiamat", "Ultima"}},
+	{"Mana", GameRegionJP, []string{"Anima", "Asura", "Chocobo", "Hades", "Ixion", "Masamune", "Pandaemonium", "Titan"}},
+	{"Meteor", GameRegionJP, []string{"Belias", "Mandragora", "Ramuh", "Shinryu", "Unicorn", "Valefor", "Yojimbo", "Zeromus"}},
+	{"Materia", GameRegionOCE, []string{"Bismarck", "Ravana", "Sephirot", "Sophia", "Zurvan"}},
+}
+
+// worlds and dataCenterIndex index the world table by lowercase name
+var (
+	worlds          = map[string]WorldInfo{}
+	dataCenterIndex = map[string]dataCenter{}
+)
+
+func init() {
+	for _, dc := range dataCenters {
+		dataCenterIndex[strings.ToLower(dc.name)] = dc
+		for _, world := range dc.worlds {
+			worlds[strings.ToLower(world)] = WorldInfo{Name: world, DataCenter: dc.name, Region: dc.region}
+		}
+	}
+}
+
+// LookupWorld returns the data center and region of a world, given its case-insensitive name
+func LookupWorld(world string) (WorldInfo, bool) {
+	info, found := worlds[strings.ToLower(strings.TrimSpace(world))]
+	return info, found
+}
+
+// ValidWorld returns whether world is a known world name
+func ValidWorld(world string) bool {
+	_, found := LookupWorld(world)
+	return found
+}
+
+// ValidDataCenter returns whether dc is a known data center name
+func ValidDataCenter(dc string) bool {
+	_, found := dataCenterIndex[strings.ToLower(strings.TrimSpace(dc))]
+	return found
+}
+
+// DataCenterRegion returns the region a data center belongs to, or an empty string if it is unknown
+func DataCenterRegion(dc string) string {
+	return dataCenterIndex[strings.ToLower(strings.TrimSpace(dc))].region
+}
+
+// DataCenterWorlds returns the names of the worlds hosted in a data center, or nil if it is unknown
+func DataCenterWorlds(dc string) []string {
+	return append([]string(nil), dataCenterIndex[strings.ToLower(strings.TrimSpace(dc))].worlds...)
+}
+
+// lodestoneRegions maps game regions to the lodestone region serving them. Oceanian worlds are served by na
+var lodestoneRegions = map[string]string{
+	GameRegionNA:  lodestone.RegionNA,
+	GameRegionEU:  lodestone.RegionEU,
+	GameRegionJP:  lodestone.RegionJP,
+	GameRegionOCE: lodestone.RegionNA,
+}
+
+// LodestoneRegion returns the lodestone region serving the given case-insensitive game region
+func LodestoneRegion(gameRegion string) (string, bool) {
+	region, found := lodestoneRegions[strings.ToUpper(strings.TrimSpace(gameRegion))]
+	return region, found
+}
+
+// withDataCenterRegion returns a copy of ctx which routes requests to the lodestone region serving the given data
+// center, unless ctx already specifies a region. The language is fixed beforehand so routing does not change it
+func (api *FFXIVAPI) withDataCenterRegion(ctx context.Context, dc string) context.Context {
+	if lodestone.RegionFromContext(ctx) != "" {
+		return ctx
+	}
+
+	region, found := LodestoneRegion(DataCenterRegion(dc))
+	if !found {
+		return ctx
+	}
+
+	ctx = lodestone.WithLanguage(ctx, api.language(ctx))
+	return lodestone.WithRegion(ctx, region)
+}
+
+// canonicalWorld returns the properly capitalized name of a known world, or ErrUnknownWorld
+func canonicalWorld(world string) (string, error) {
+	info, found := LookupWorld(world)
+	if !found {
+		return "", fmt.Errorf("%w: %q", ErrUnknownWorld, world)
+	}
+
+	return info.Name, nil
+}
+
+// canonicalDataCenter returns the properly capitalized name of a known data center, or ErrUnknownWorld
+func canonicalDataCenter(dc string) (string, error) {
+	d, found := dataCenterIndex[strings.ToLower(strings.TrimSpace(dc))]
+	if !found {
+		return "", fmt.Errorf("%w: %q", ErrUnknownWorld, dc)
+	}
+
+	return d.name, nil
+}
+
+// worldDCRegex splits the world and data center as displayed by the lodestone, e.g. "Ragnarok (Chaos)" or "Ragnarok [Chaos]"
+var worldDCRegex = regexp.MustCompile(`^(.+?)\s*[(\[](.+)[)\]]$`)
+
+// splitWorld returns the world, data center and region from a lodestone world string, completing missing data from the
+// world table if possible
+func splitWorld(s string) WorldInfo {
+	s = strings.TrimSpace(s)
+	info := WorldInfo{Name: s}
+
+	matches := worldDCRegex.FindStringSubmatch(s)
+	if len(matches) >= 3 {
+		info.Name = matches[1]
+		info.DataCenter = matches[2]
+	}
+
+	if known, found := LookupWorld(info.Name); found {
+		return known
+	}
+
+	info.Region = DataCenterRegion(info.DataCenter)
+	return info
+}
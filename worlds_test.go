@@ -0,0 +1,98 @@
+package ffxivapi
+
+import (
+	"errors"
+	"roob.re/ffxivapi/lodestone"
+	"testing"
+)
+
+func TestSplitWorld(t *testing.T) {
+	for _, tc := range []struct {
+		raw      string
+		expected WorldInfo
+	}{
+		{raw: "Ragnarok (Chaos)", expected: WorldInfo{Name: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU}},
+		{raw: "Ragnarok [Chaos]", expected: WorldInfo{Name: "Ragnarok", DataCenter: "Chaos", Region: GameRegionEU}},
+		{raw: " Gilgamesh (Aether) ", expected: WorldInfo{Name: "Gilgamesh", DataCenter: "Aether", Region: GameRegionNA}},
+		{raw: "Ravana", expected: WorldInfo{Name: "Ravana", DataCenter: "Materia", Region: GameRegionOCE}},
+		{raw: "tonberry", expected: WorldInfo{Name: "Tonberry", DataCenter: "Elemental", Region: GameRegionJP}},
+		// Worlds missing from the table keep whatever the lodestone displays
+		{raw: "Newworld (Light)", expected: WorldInfo{Name: "Newworld", DataCenter: "Light", Region: GameRegionEU}},
+		{raw: "Newworld (Newdc)", expected: WorldInfo{Name: "Newworld", DataCenter: "Newdc"}},
+		{raw: "Newworld", expected: WorldInfo{Name: "Newworld"}},
+	} {
+		if info := splitWorld(tc.raw); info != tc.expected {
+			t.Errorf("%q: expected %+v, got %+v", tc.raw, tc.expected, info)
+		}
+	}
+}
+
+func TestCanonicalWorld(t *testing.T) {
+	for _, tc := range []struct {
+		world    string
+		expected string
+		err      bool
+	}{
+		{world: "Ragnarok", expected: "Ragnarok"},
+		{world: "ragnarok", expected: "Ragnarok"},
+		{world: " PHANTOM ", expected: "Phantom"},
+		{world: "Chaos", err: true},
+		{world: "", err: true},
+	} {
+		world, err := canonicalWorld(tc.world)
+		if tc.err {
+			if !errors.Is(err, ErrUnknownWorld) {
+				t.Errorf("%q: expected %v, got %q and %v", tc.world, ErrUnknownWorld, world, err)
+			}
+			continue
+		}
+
+		if err != nil || world != tc.expected {
+			t.Errorf("%q: expected %q, got %q and %v", tc.world, tc.expected, world, err)
+		}
+	}
+}
+
+func TestCanonicalDataCenter(t *testing.T) {
+	for _, tc := range []struct {
+		dc       string
+		expected string
+		err      bool
+	}{
+		{dc: "Chaos", expected: "Chaos"},
+		{dc: "materia", expected: "Materia"},
+		{dc: "Ragnarok", err: true},
+	} {
+		dc, err := canonicalDataCenter(tc.dc)
+		if tc.err {
+			if !errors.Is(err, ErrUnknownWorld) {
+				t.Errorf("%q: expected %v, got %q and %v", tc.dc, ErrUnknownWorld, dc, err)
+			}
+			continue
+		}
+
+		if err != nil || dc != tc.expected {
+			t.Errorf("%q: expected %q, got %q and %v", tc.dc, tc.expected, dc, err)
+		}
+	}
+}
+
+func TestLodestoneRegion(t *testing.T) {
+	for _, tc := range []struct {
+		gameRegion string
+		expected   string
+		found      bool
+	}{
+		{gameRegion: GameRegionEU, expected: lodestone.RegionEU, found: true},
+		{gameRegion: GameRegionNA, expected: lodestone.RegionNA, found: true},
+		{gameRegion: GameRegionJP, expected: lodestone.RegionJP, found: true},
+		{gameRegion: GameRegionOCE, expected: lodestone.RegionNA, found: true},
+		{gameRegion: "oce", expected: lodestone.RegionNA, found: true},
+		{gameRegion: "de"},
+	} {
+		region, found := LodestoneRegion(tc.gameRegion)
+		if region != tc.expected || found != tc.found {
+			t.Errorf("%q: expected %q (found: %v), got %q (found: %v)", tc.gameRegion, tc.expected, tc.found, region, found)
+		}
+	}
+}